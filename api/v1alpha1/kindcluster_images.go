@@ -0,0 +1,173 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BuiltinImages are the kindest/node images that were released for kind v0.11.1, they are
+// available without a KINDImageCatalog
+var BuiltinImages = []KINDImage{
+	{Version: "1.22", Image: "kindest/node:v1.22.0", Digest: "sha256:b8bda84bb3a190e6e028b1760d277454a72267a5454b57db34437c34a588d047"},
+	{Version: "1.21", Image: "kindest/node:v1.21.1", Digest: "sha256:69860bda5563ac81e3c0057d654b5253219618a22ec3a346306239bba8cfa1a6"},
+	{Version: "1.20", Image: "kindest/node:v1.20.7", Digest: "sha256:cbeaf907fc78ac97ce7b625e4bf0de16e3ea725daf6b04f930bd14c67c671ff9"},
+	{Version: "1.19", Image: "kindest/node:v1.19.11", Digest: "sha256:07db187ae84b4b7de440a73886f008cf903fcf5764ba8106a9fd5243d6f32729"},
+	{Version: "1.18", Image: "kindest/node:v1.18.19", Digest: "sha256:7af1492e19b3192a79f606e43c35fb741e520d195f96399284515f077b3b622c"},
+	{Version: "1.17", Image: "kindest/node:v1.17.17", Digest: "sha256:66f1d0d91a88b8a001811e2f1054af60eef3b669a9a74f9b6db871f2f1eeed00"},
+	{Version: "1.16", Image: "kindest/node:v1.16.15", Digest: "sha256:83067ed51bf2a3395b24687094e283a7c7c865ccc12a8b1d7aa673ba0c5e8861"},
+	{Version: "1.15", Image: "kindest/node:v1.15.12", Digest: "sha256:b920920e1eda689d9936dfcf7332701e80be12566999152626b2c9d730397a95"},
+	{Version: "1.14", Image: "kindest/node:v1.14.10", Digest: "sha256:f8a66ef82822ab4f7569e91a5bccaf27bceee135c1457c512e54de8c6f7219f8"},
+}
+
+// DefaultKubernetesVersion is the Kubernetes version of the KINDClusters that do not specify
+// a version, it is set from the configuration of the controller
+var DefaultKubernetesVersion = "1.21"
+
+// ImageCatalog maps the supported Kubernetes versions to their node images
+type ImageCatalog map[string]KINDImage
+
+// NewImageCatalog builds the image catalog from the built-in images and the KINDImageCatalogs,
+// the images of the catalogs replace the built-in images of the same versions
+// The catalogs are applied in the order of their names, so the result does not depend on
+// the order in which they are listed.
+func NewImageCatalog(catalogs []KINDImageCatalog) ImageCatalog {
+	imageCatalog := ImageCatalog{}
+
+	for _, image := range BuiltinImages {
+		imageCatalog[image.Version] = image
+	}
+
+	sorted := make([]KINDImageCatalog, len(catalogs))
+	copy(sorted, catalogs)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, catalog := range sorted {
+		for _, image := range catalog.Spec.Images {
+			imageCatalog[image.Version] = image
+		}
+	}
+
+	return imageCatalog
+}
+
+// LoadImageCatalog lists the KINDImageCatalogs and builds the image catalog from them
+func LoadImageCatalog(ctx context.Context, c client.Reader) (ImageCatalog, error) {
+	var catalogs KINDImageCatalogList
+
+	if err := c.List(ctx, &catalogs); err != nil {
+		return nil, err
+	}
+
+	return NewImageCatalog(catalogs.Items), nil
+}
+
+// Resolve returns the node image of the Kubernetes version, the version can have a
+// leading v
+// A patch version that is not in the catalog uses the image of its minor version if the
+// tag of that image is the patch version. Other patch versions are not resolved, because
+// it is not known whether their images exist, they must be added to a KINDImageCatalog.
+func (c ImageCatalog) Resolve(version string) (KINDImage, bool) {
+	version = strings.TrimPrefix(version, "v")
+
+	if image, ok := c[version]; ok {
+		return image, true
+	}
+
+	parts := strings.Split(version, ".")
+
+	if len(parts) != 3 {
+		return KINDImage{}, false
+	}
+
+	minorImage, ok := c[parts[0]+"."+parts[1]]
+
+	if !ok {
+		return KINDImage{}, false
+	}
+
+	if _, tag := splitImage(minorImage.Image); tag != "v"+version {
+		return KINDImage{}, false
+	}
+
+	return minorImage, true
+}
+
+// Image returns the reference of the node image of the Kubernetes version
+func (c ImageCatalog) Image(version string) (string, bool) {
+	image, ok := c.Resolve(version)
+
+	if !ok {
+		return "", false
+	}
+
+	return image.Reference(), true
+}
+
+// IsVersionImage returns true if the image is the node image of one of the versions, the
+// built-in images are also checked because they may have been replaced by a catalog
+func (c ImageCatalog) IsVersionImage(image string) bool {
+	for _, versionImage := range c {
+		if image == versionImage.Reference() {
+			return true
+		}
+	}
+
+	for _, versionImage := range BuiltinImages {
+		if image == versionImage.Reference() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Versions returns the sorted Kubernetes versions of the catalog
+func (c ImageCatalog) Versions() []string {
+	versions := make([]string, 0, len(c))
+
+	for version := range c {
+		versions = append(versions, version)
+	}
+
+	sort.Strings(versions)
+
+	return versions
+}
+
+// Split the image reference into its repository and its tag, the digest is dropped
+func splitImage(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	// A colon before the last slash belongs to the port of the registry
+	i := strings.LastIndex(image, ":")
+
+	if i < 0 || i < strings.LastIndex(image, "/") {
+		return image, ""
+	}
+
+	return image[:i], image[i+1:]
+}
@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_NewImageCatalog(t *testing.T) {
+	catalogs := []KINDImageCatalog{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b-catalog"},
+			Spec: KINDImageCatalogSpec{Images: []KINDImage{
+				{Version: "1.23", Image: "registry.local/node:v1.23.0"},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-catalog"},
+			Spec: KINDImageCatalogSpec{Images: []KINDImage{
+				{Version: "1.23", Image: "kindest/node:v1.23.0"},
+				{Version: "1.14", Image: "kindest/node:v1.14.10", Deprecated: true},
+			}},
+		},
+	}
+
+	catalog := NewImageCatalog(catalogs)
+
+	var testCases = []struct {
+		version    string
+		image      string
+		ok         bool
+		deprecated bool
+	}{
+		{"1.21", "kindest/node:v1.21.1@sha256:69860bda5563ac81e3c0057d654b5253219618a22ec3a346306239bba8cfa1a6", true, false},
+		{"1.23", "registry.local/node:v1.23.0", true, false},
+		{"1.14", "kindest/node:v1.14.10", true, true},
+		{"1.99", "", false, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version, func(t *testing.T) {
+			image, ok := catalog.Image(tc.version)
+
+			if image != tc.image || ok != tc.ok || catalog[tc.version].Deprecated != tc.deprecated {
+				t.Errorf("Image() = %v, %v, deprecated %v, want %v, %v, deprecated %v",
+					image, ok, catalog[tc.version].Deprecated, tc.image, tc.ok, tc.deprecated)
+			}
+		})
+	}
+
+	// The replaced built-in image is still recognized as an image of a version
+	if !catalog.IsVersionImage("kindest/node:v1.14.10@sha256:f8a66ef82822ab4f7569e91a5bccaf27bceee135c1457c512e54de8c6f7219f8") {
+		t.Errorf("IsVersionImage() = false, want true for the replaced built-in image")
+	}
+}
+
+func Test_ResolveImage(t *testing.T) {
+	catalog := NewImageCatalog([]KINDImageCatalog{{
+		Spec: KINDImageCatalogSpec{Images: []KINDImage{
+			{Version: "1.21.14", Image: "registry.local/node:v1.21.14"},
+		}},
+	}})
+
+	var testCases = []struct {
+		version string
+		image   string
+		ok      bool
+	}{
+		{"1.20", "kindest/node:v1.20.7@sha256:cbeaf907fc78ac97ce7b625e4bf0de16e3ea725daf6b04f930bd14c67c671ff9", true},
+		{"v1.20.7", "kindest/node:v1.20.7@sha256:cbeaf907fc78ac97ce7b625e4bf0de16e3ea725daf6b04f930bd14c67c671ff9", true},
+		{"v1.20.999", "", false},
+		{"v1.21.14", "registry.local/node:v1.21.14", true},
+		{"v1.99.0", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version, func(t *testing.T) {
+			if image, ok := catalog.Image(tc.version); image != tc.image || ok != tc.ok {
+				t.Errorf("Image() = %v, %v, want %v, %v", image, ok, tc.image, tc.ok)
+			}
+		})
+	}
+
+	// The image of a patch version of the catalog follows the changes of the version
+	if !catalog.IsVersionImage("registry.local/node:v1.21.14") {
+		t.Errorf("IsVersionImage() = false, want true for the image of a patch version")
+	}
+
+	if catalog.IsVersionImage("registry.local/custom:v1.20.15") {
+		t.Errorf("IsVersionImage() = true, want false for a custom image")
+	}
+}
@@ -22,15 +22,103 @@ import (
 
 var KindOfKindCluster = "KINDCluster"
 
-type KindClusterCondition struct {
+// KINDClusterPhase represents the lifecycle phase of the KIND Cluster
+//+kubebuilder:validation:Enum=Pending;Provisioning;Provisioned;Deleting;Failed
+type KINDClusterPhase string
+
+const (
+	// KINDClusterPhasePending means that the KINDCluster was accepted by the controller,
+	// but the creation of the cluster has not started yet
+	KINDClusterPhasePending KINDClusterPhase = "Pending"
+
+	// KINDClusterPhaseProvisioning means that the cluster is being created
+	KINDClusterPhaseProvisioning KINDClusterPhase = "Provisioning"
+
+	// KINDClusterPhaseProvisioned means that the cluster exists
+	KINDClusterPhaseProvisioned KINDClusterPhase = "Provisioned"
+
+	// KINDClusterPhaseDeleting means that the KINDCluster is in deletion and the
+	// cluster is being deleted
+	KINDClusterPhaseDeleting KINDClusterPhase = "Deleting"
+
+	// KINDClusterPhaseFailed means that the cluster cannot be created
+	KINDClusterPhaseFailed KINDClusterPhase = "Failed"
+)
+
+// DriftPolicy specifies how the controller acts when the running cluster does not match the spec
+type DriftPolicy string
+
+const (
+	// DriftPolicyIgnore does not detect the drift
+	DriftPolicyIgnore DriftPolicy = "Ignore"
+
+	// DriftPolicyReport reports the drift in the status
+	DriftPolicyReport DriftPolicy = "Report"
+
+	// DriftPolicyRecreate deletes the drifted cluster and creates it again from the spec
+	DriftPolicyRecreate DriftPolicy = "Recreate"
+)
+
+// Types of the status conditions of KINDCluster
+const (
+	// ClusterProvisionedCondition reports whether the kind cluster exists
+	ClusterProvisionedCondition = "ClusterProvisioned"
+
+	// KubeconfigAvailableCondition reports whether the kubeconfig of the cluster is stored in the secrets
+	KubeconfigAvailableCondition = "KubeconfigAvailable"
+
+	// NodesHealthyCondition reports whether the running nodes of the cluster match the spec
+	NodesHealthyCondition = "NodesHealthy"
+
+	// RegistryAvailableCondition reports whether the local registry of the cluster is running
+	// and announced in the cluster
+	RegistryAvailableCondition = "RegistryAvailable"
+
+	// NodesReadyCondition reports whether the API server of the cluster is ready and all
+	// nodes are registered and ready, it is probed periodically
+	NodesReadyCondition = "NodesReady"
+
+	// ImagesPreloadedCondition reports whether the preload images are loaded into all nodes
+	ImagesPreloadedCondition = "ImagesPreloaded"
+
+	// AddonsAppliedCondition reports whether all addons are applied into the cluster
+	AddonsAppliedCondition = "AddonsApplied"
+
+	// AddonAppliedCondition reports whether an addon is applied, it is a condition of the
+	// status of the addon
+	AddonAppliedCondition = "Applied"
+
+	// RegistryAuthAppliedCondition reports whether the registry credentials are written into
+	// all nodes of the cluster
+	RegistryAuthAppliedCondition = "RegistryAuthApplied"
+
+	// KindConfigValidCondition reports whether the kind cluster configuration of the spec
+	// can be parsed and merged with the typed fields of the spec
+	KindConfigValidCondition = "KindConfigValid"
+
+	// KubernetesVersionSupportedCondition reports whether the kubernetes version is in the image catalog
+	KubernetesVersionSupportedCondition = "KubernetesVersionSupported"
+
+	// ReadyCondition reports whether the resource is ready to be used
+	ReadyCondition = "Ready"
+)
+
+// KINDStatusEvent is a record of a change of a status condition
+type KINDStatusEvent struct {
 	// Represents the time when the event occurred
 	Timestamp metav1.Time `json:"timestamp,omitempty"`
 
-	// Represents the specific message for the event
-	Message string `json:"message,omitempty"`
+	// Represents the type of the condition that changed
+	Type string `json:"type,omitempty"`
+
+	// Represents the new status of the condition
+	Status metav1.ConditionStatus `json:"status,omitempty"`
 
 	// Represents the detailed reason for the event
 	Reason string `json:"reason,omitempty"`
+
+	// Represents the specific message for the event
+	Message string `json:"message,omitempty"`
 }
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -43,13 +131,419 @@ type KINDClusterSpec struct {
 
 	//+kubebuilder:validation:MaxLength=64
 	// Specifies the cluster name, the KIND Cluster will be created with this name
-	// It has not an omitempty tag, so this field is required
-	ClusterName string `json:"clusterName"`
+	// If it is not specified, it is defaulted from the namespace and the name of the
+	// KINDCluster, long names are shortened with a hash suffix. The cluster name of a
+	// KINDCluster that is created with generateName is set by the controller.
+	ClusterName string `json:"clusterName,omitempty"`
 
-	//+kubebuilder:validation:Enum="1.22";"1.21";"1.20";"1.19";"1.18";"1.17";"1.16";"1.15";"1.14"
-	//+kubebuilder:default="1.21"
+	//+kubebuilder:validation:Pattern=`^v?[0-9]+\.[0-9]+(\.[0-9]+)?$`
 	// Specifies the kubernetes version, the KIND Cluster will be created with this version
+	// It is a minor version such as 1.21 or a patch version such as v1.21.14. If it is not
+	// specified, the default version of the controller is used. The version must be in the
+	// image catalog, see KINDImageCatalog, a patch version can also use the image
+	// repository of its minor version
 	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// Specifies the node image of the cluster, the node images of the topology take
+	// precedence over it
+	// It is defaulted to the kindest/node image of the kubernetes version pinned by its
+	// digest, so that the cluster is reproducible. The defaulted image follows the changes
+	// of the kubernetes version, an image that is set explicitly is kept.
+	NodeImage string `json:"nodeImage,omitempty"`
+
+	// Specifies the node topology of the cluster, the numbers of control-plane and worker nodes
+	// If it is not specified, the KIND Cluster will be created with a single control-plane node
+	Topology *KINDClusterTopology `json:"topology,omitempty"`
+
+	// Specifies the networking options of the cluster
+	// The options that are not specified are defaulted by the kind tool
+	Networking *KINDClusterNetworking `json:"networking,omitempty"`
+
+	// Specifies the host ports of an ingress controller, the ports 80 and 443 of the first
+	// control-plane node are published on the host and the node is labeled ingress-ready=true
+	Ingress *KINDClusterIngress `json:"ingress,omitempty"`
+
+	// Specifies the local registry of the cluster, the registry container is shared by the
+	// clusters that use the same registry name and it is not deleted with them
+	// The nodes pull the images of localhost:<hostPort> from the registry, and the registry
+	// is announced in the local-registry-hosting ConfigMap of the kube-public namespace.
+	Registry *KINDClusterRegistry `json:"registry,omitempty"`
+
+	// Specifies the feature gates of the Kubernetes components, for example to enable
+	// alpha features
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// Specifies the API groups and versions that are enabled or disabled in the API server,
+	// for example "api/alpha": "true"
+	RuntimeConfig map[string]string `json:"runtimeConfig,omitempty"`
+
+	// Specifies the kubeadm configuration patches of all nodes in YAML, for example to set
+	// flags of the API server or settings of the kubelet
+	// Each patch is a strategic merge patch of a kubeadm configuration kind such as
+	// ClusterConfiguration, InitConfiguration, JoinConfiguration or KubeletConfiguration.
+	KubeadmConfigPatches []string `json:"kubeadmConfigPatches,omitempty"`
+
+	// Specifies the containerd configuration patches of all nodes in TOML, for example to
+	// pull the images through the mirrors of an internal registry
+	ContainerdConfigPatches []string `json:"containerdConfigPatches,omitempty"`
+
+	// Specifies a Secret in the namespace of the KINDCluster with the credentials of the
+	// private registries, they are written into the nodes for the kubelet after the cluster
+	// is created and written again when the Secret changes or nodes are added
+	RegistryAuth *RegistryAuthReference `json:"registryAuth,omitempty"`
+
+	// Specifies the images that are loaded into all nodes after the cluster is created, so
+	// the workloads do not need to pull them from a registry
+	// An image is either the reference of an image of the container runtime of the
+	// controller host or the absolute path of an image archive on the controller host.
+	// The images that fail to load are retried without recreating the cluster.
+	PreloadImages []string `json:"preloadImages,omitempty"`
+
+	//+listType=map
+	//+listMapKey=name
+	// Specifies the addons that are applied into the cluster after it is created, for
+	// example the CNI, the ingress controller or CRDs
+	// The addons are applied in their order with server-side apply, an addon is applied
+	// only after all addons before it are applied. An addon is applied again when its
+	// manifests change. The objects of a removed addon are not deleted from the cluster.
+	Addons []KINDClusterAddon `json:"addons,omitempty"`
+
+	// Specifies the kind cluster configuration in the kind.x-k8s.io/v1alpha4 format, for the
+	// options of kind that are not modeled in the spec
+	// The typed fields of the spec are merged on top of it, a field that is set in both with
+	// different values is a conflict that is reported in the status. It cannot be set
+	// together with kindConfigRef.
+	KindConfig string `json:"kindConfig,omitempty"`
+
+	// Specifies a ConfigMap in the namespace of the KINDCluster that holds the kind cluster
+	// configuration, it is merged in the same way as kindConfig
+	KindConfigRef *KindConfigReference `json:"kindConfigRef,omitempty"`
+
+	//+kubebuilder:validation:Enum=Ignore;Report;Recreate
+	//+kubebuilder:default=Report
+	// Specifies how the controller acts when the running cluster does not match the spec,
+	// for example after the kubernetes version or the topology was changed
+	// Ignore: nothing is done, Report: the drift is reported in the status,
+	// Recreate: the cluster is deleted and created again with the current spec
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Represents the endpoint of the API server of the cluster
+	// It is set by the controller when the cluster is provisioned, as required
+	// by the Cluster API infrastructure cluster contract
+	ControlPlaneEndpoint APIEndpoint `json:"controlPlaneEndpoint,omitempty"`
+}
+
+// KINDClusterIngress defines the host ports of the ingress controller of the cluster
+type KINDClusterIngress struct {
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=65535
+	// Specifies the host port of HTTP, it is allocated from the host port range of the
+	// controller if it is not specified
+	HTTPPort int32 `json:"httpPort,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=65535
+	// Specifies the host port of HTTPS, it is allocated from the host port range of the
+	// controller if it is not specified
+	HTTPSPort int32 `json:"httpsPort,omitempty"`
+}
+
+// KINDClusterRegistry defines the local registry of the cluster
+type KINDClusterRegistry struct {
+	//+kubebuilder:default=kind-registry
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`
+	// Specifies the name of the registry container
+	Name string `json:"name,omitempty"`
+
+	//+kubebuilder:default=5001
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// Specifies the port on the host that the registry is published on, on the loopback address
+	HostPort int32 `json:"hostPort,omitempty"`
+}
+
+// KINDClusterAddon defines an addon of the cluster, the YAML manifests of the addon are read
+// from a ConfigMap or a Secret in the namespace of the KINDCluster
+type KINDClusterAddon struct {
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	//+kubebuilder:validation:MaxLength=63
+	// Specifies the name of the addon
+	Name string `json:"name"`
+
+	// Specifies the ConfigMap that holds the manifests, it cannot be set together with secretRef
+	ConfigMapRef *AddonSourceReference `json:"configMapRef,omitempty"`
+
+	// Specifies the Secret that holds the manifests, for the addons that contain credentials
+	// It cannot be set together with configMapRef.
+	SecretRef *AddonSourceReference `json:"secretRef,omitempty"`
+}
+
+// AddonSourceReference refers to the ConfigMap or the Secret of the manifests of an addon
+type AddonSourceReference struct {
+	//+kubebuilder:validation:MinLength=1
+	// Specifies the name of the ConfigMap or the Secret
+	Name string `json:"name"`
+
+	// Specifies the key of the manifests, all keys are applied in the order of their names
+	// if it is not specified
+	Key string `json:"key,omitempty"`
+}
+
+// RegistryAuthReference refers to a Secret that holds the credentials of private registries
+type RegistryAuthReference struct {
+	//+kubebuilder:validation:MinLength=1
+	// Specifies the name of the Secret, it must be of the kubernetes.io/dockerconfigjson
+	// type, with the credentials in the .dockerconfigjson key
+	SecretName string `json:"secretName"`
+}
+
+// KindConfigReference refers to the key of a ConfigMap that holds a kind cluster configuration
+type KindConfigReference struct {
+	//+kubebuilder:validation:MinLength=1
+	// Specifies the name of the ConfigMap
+	Name string `json:"name"`
+
+	//+kubebuilder:default=config
+	// Specifies the key of the configuration in the ConfigMap
+	Key string `json:"key,omitempty"`
+}
+
+// APIEndpoint represents a reachable Kubernetes API endpoint
+type APIEndpoint struct {
+	// Specifies the hostname or the IP address on which the API server is serving
+	Host string `json:"host"`
+
+	// Specifies the port on which the API server is serving
+	Port int32 `json:"port"`
+}
+
+// IsZero returns true if both host and port are zero values
+func (e APIEndpoint) IsZero() bool {
+	return e.Host == "" && e.Port == 0
+}
+
+// FailureDomainSpec is the Cluster API representation of a failure domain
+type FailureDomainSpec struct {
+	// Specifies whether the failure domain is suitable for control-plane nodes
+	ControlPlane bool `json:"controlPlane,omitempty"`
+
+	// Specifies the attributes of the failure domain
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// KINDClusterNetworking defines the networking options of the KIND Cluster
+type KINDClusterNetworking struct {
+	//+kubebuilder:validation:Enum=ipv4;ipv6;dual
+	// Specifies the IP family of the cluster
+	IPFamily string `json:"ipFamily,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[0-9a-fA-F:.]+$`
+	// Specifies the listen address of the API server on the host
+	APIServerAddress string `json:"apiServerAddress,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=65535
+	// Specifies the listen port of the API server on the host
+	// If it is zero, a port is allocated from the host port range of the controller, or a
+	// random port is picked if no range is configured
+	APIServerPort int32 `json:"apiServerPort,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[0-9a-fA-F:.]+/[0-9]{1,3}(,[0-9a-fA-F:.]+/[0-9]{1,3})?$`
+	// Specifies the CIDR of the pod network, two comma separated CIDRs for dual stack
+	PodSubnet string `json:"podSubnet,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[0-9a-fA-F:.]+/[0-9]{1,3}(,[0-9a-fA-F:.]+/[0-9]{1,3})?$`
+	// Specifies the CIDR of the service network, two comma separated CIDRs for dual stack
+	ServiceSubnet string `json:"serviceSubnet,omitempty"`
+
+	// Specifies whether the default CNI (kindnetd) is installed or not
+	// It can be disabled to install another CNI such as Calico or Cilium
+	DisableDefaultCNI bool `json:"disableDefaultCNI,omitempty"`
+
+	//+kubebuilder:validation:Enum=iptables;ipvs
+	// Specifies the mode of kube-proxy
+	KubeProxyMode string `json:"kubeProxyMode,omitempty"`
+}
+
+// KINDClusterTopology defines the nodes that the KIND Cluster consists of
+type KINDClusterTopology struct {
+	// Specifies the control-plane nodes of the cluster
+	// More than one control-plane node results in an HA cluster behind a load balancer
+	ControlPlane ControlPlaneTopology `json:"controlPlane,omitempty"`
+
+	// Specifies the worker nodes of the cluster
+	Workers WorkerTopology `json:"workers,omitempty"`
+}
+
+// ControlPlaneTopology defines the control-plane nodes of the KIND Cluster
+type ControlPlaneTopology struct {
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=1
+	// Specifies the number of control-plane nodes
+	Replicas int32 `json:"replicas,omitempty"`
+
+	KINDNodeTemplate `json:",inline"`
+}
+
+// WorkerTopology defines the worker nodes of the KIND Cluster
+type WorkerTopology struct {
+	//+kubebuilder:validation:Minimum=0
+	// Specifies the number of worker nodes
+	Replicas int32 `json:"replicas,omitempty"`
+
+	KINDNodeTemplate `json:",inline"`
+}
+
+// KINDNodeTemplate defines the settings shared by all nodes of a role
+type KINDNodeTemplate struct {
+	// Specifies the node image of the nodes of the role, it overrides the node image
+	// of the cluster, for example to test a custom-built image on the workers only
+	NodeImage string `json:"nodeImage,omitempty"`
+
+	// Specifies the labels that will be added to the nodes
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Specifies the kubeadm configuration patches of the nodes of the role in YAML, they
+	// are applied after the patches of the cluster
+	KubeadmConfigPatches []string `json:"kubeadmConfigPatches,omitempty"`
+
+	// Specifies the ports of the node containers that are published on the host, for
+	// example the ports of an ingress controller
+	// A host port can only be mapped on a role with a single node, and it must not be
+	// used by another KINDCluster.
+	ExtraPortMappings []PortMapping `json:"extraPortMappings,omitempty"`
+
+	// Specifies the host paths that will be mounted into the node containers, for example
+	// a source tree or a CA bundle
+	ExtraMounts []Mount `json:"extraMounts,omitempty"`
+}
+
+// PortMapping defines a port of a node container that is published on the host
+type PortMapping struct {
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// Specifies the port in the node container
+	ContainerPort int32 `json:"containerPort"`
+
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=65535
+	// Specifies the port on the host, a random port is used if it is not specified
+	HostPort int32 `json:"hostPort,omitempty"`
+
+	// Specifies the host address on which the port is published, all addresses are
+	// used if it is not specified
+	ListenAddress string `json:"listenAddress,omitempty"`
+
+	//+kubebuilder:validation:Enum=TCP;UDP;SCTP
+	//+kubebuilder:default=TCP
+	// Specifies the protocol of the port
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// KINDClusterOperation defines the state of a long running operation on the KIND Cluster
+type KINDClusterOperation struct {
+	// Represents the type of the operation, for example Create
+	Type string `json:"type"`
+
+	// Represents the time when the operation started
+	StartTime metav1.Time `json:"startTime"`
+
+	// Represents the current step of the operation, as reported by the kind tool
+	Step string `json:"step,omitempty"`
+}
+
+// KINDNodeStatus defines the observed state of a node of the KIND Cluster
+type KINDNodeStatus struct {
+	// Represents the name of the node container
+	Name string `json:"name"`
+
+	// Represents the role of the node: control-plane, worker or external-load-balancer
+	Role string `json:"role,omitempty"`
+}
+
+// KINDPortStatus defines a host port that is used by the KIND Cluster
+type KINDPortStatus struct {
+	// Represents the role of the nodes that publish the port
+	Role string `json:"role"`
+
+	// Represents the port in the node container
+	ContainerPort int32 `json:"containerPort"`
+
+	// Represents the port on the host
+	HostPort int32 `json:"hostPort"`
+
+	// Represents the host address on which the port is published
+	ListenAddress string `json:"listenAddress,omitempty"`
+
+	// Represents the protocol of the port
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// ImageLoadState represents the load state of a preload image
+//+kubebuilder:validation:Enum=Loading;Loaded;Failed
+type ImageLoadState string
+
+const (
+	// ImageLoadStateLoading means that the image is being loaded into the nodes in the background
+	ImageLoadStateLoading ImageLoadState = "Loading"
+
+	// ImageLoadStateLoaded means that the image is loaded into all nodes
+	ImageLoadStateLoaded ImageLoadState = "Loaded"
+
+	// ImageLoadStateFailed means that the last attempt to load the image failed, it is retried
+	ImageLoadStateFailed ImageLoadState = "Failed"
+)
+
+// KINDImageLoadStatus defines the load status of a preload image of the KIND Cluster
+type KINDImageLoadStatus struct {
+	// Represents the image reference or the image archive path
+	Image string `json:"image"`
+
+	// Represents the load state of the image
+	State ImageLoadState `json:"state"`
+
+	// Represents the error of the last failed attempt
+	Message string `json:"message,omitempty"`
+
+	// Represents the number of attempts to load the image
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// Represents the time of the last attempt to load the image
+	LastAttemptTime metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// Represents the hash of the names of the nodes that the image was last loaded into,
+	// the image is loaded again when the hash changes, for example when a node is added
+	NodesHash string `json:"nodesHash,omitempty"`
+}
+
+// KINDAddonStatus defines the status of an addon of the KIND Cluster
+type KINDAddonStatus struct {
+	// Represents the name of the addon
+	Name string `json:"name"`
+
+	// Represents the hash of the manifests that were last applied, the addon is applied
+	// again when the hash of its manifests changes
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// Represents the number of objects that were last applied
+	Objects int32 `json:"objects,omitempty"`
+
+	// Represents the time of the last successful application of the addon
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	//+listType=map
+	//+listMapKey=type
+	// Represents the conditions of the addon
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KINDRegistryStatus defines the endpoints of the local registry of the KIND Cluster
+type KINDRegistryStatus struct {
+	// Represents the endpoint of the registry on the host, the images are pushed to it
+	Endpoint string `json:"endpoint"`
+
+	// Represents the endpoint of the registry in the network of the nodes
+	InternalEndpoint string `json:"internalEndpoint"`
 }
 
 // KINDClusterStatus defines the observed state of KINDCluster
@@ -59,16 +553,96 @@ type KINDClusterStatus struct {
 
 	// Represents the state of cluster
 	// true for ready cluster, false for unready/uncreated cluster
-	// The information about whether the cluster is ready or not is obtained
-	// by relying on the Kind library functions.
+	// The cluster is ready when it exists, its kubeconfig is stored and, if the health of the
+	// clusters is probed, its API server and all of its nodes are ready.
 	Ready *bool `json:"ready,omitempty"`
 
+	// Represents the lifecycle phase of the cluster
+	Phase KINDClusterPhase `json:"phase,omitempty"`
+
+	// Represents the operation that is running on the cluster in the background
+	Operation *KINDClusterOperation `json:"operation,omitempty"`
+
 	// Represents the failure reason of the cluster creation,
 	// it reports the error that returned from the kind tool
 	FailureMessage string `json:"failureMessage,omitempty"`
 
-	// Represents the status conditions, they are important to see the historical infromation
-	Conditions []KindClusterCondition `json:"conditions,omitempty"`
+	// Represents the terminal failure reason of the cluster in a format
+	// that Cluster API can interpret, for example CreateError
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// Represents the failure domains of the cluster
+	// All nodes of a kind cluster run on the same host, so no failure domain is reported
+	FailureDomains map[string]FailureDomainSpec `json:"failureDomains,omitempty"`
+
+	// Represents the SHA-256 hash of the current kubeconfig of the cluster, it changes when
+	// the certificates or the endpoint of the cluster change
+	KubeconfigHash string `json:"kubeconfigHash,omitempty"`
+
+	// Represents the name of the secret that the kubeconfig is stored in with the Cluster API
+	// format, the secret is deleted by this name even if the owner Cluster changed
+	KubeconfigSecretName string `json:"kubeconfigSecretName,omitempty"`
+
+	// Represents the generation of the spec that was last reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	//+listType=map
+	//+listMapKey=type
+	// Represents the current status conditions of the cluster
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Represents the last changes of the status conditions, they are important to see
+	// the historical information
+	// Only the most recent events are kept.
+	History []KINDStatusEvent `json:"history,omitempty"`
+
+	// Represents the actual nodes of the cluster
+	Nodes []KINDNodeStatus `json:"nodes,omitempty"`
+
+	// Represents the number of the Kubernetes nodes of the cluster, the load balancer
+	// node is not counted
+	NodeCount int32 `json:"nodeCount,omitempty"`
+
+	// Represents the effective node images of the cluster by node role, after the node
+	// images of the kind configuration, the topology, the node image of the cluster and the
+	// image catalog are resolved
+	NodeImages map[string]string `json:"nodeImages,omitempty"`
+
+	// Represents the effective networking options of the cluster,
+	// including the values that were defaulted by the kind tool
+	Networking *KINDClusterNetworking `json:"networking,omitempty"`
+
+	// Represents the host ports that are published by the nodes of the cluster, the API
+	// server port is reported in the networking options
+	Ports []KINDPortStatus `json:"ports,omitempty"`
+
+	// Represents the endpoints of the local registry of the cluster
+	Registry *KINDRegistryStatus `json:"registry,omitempty"`
+
+	// Represents the hash of the registry credentials and of the nodes that they were
+	// written into, the credentials are written again when it changes
+	RegistryAuthHash string `json:"registryAuthHash,omitempty"`
+
+	//+listType=map
+	//+listMapKey=image
+	// Represents the load status of the preload images
+	// It is reset when the cluster is recreated, so the images are loaded into the new nodes.
+	PreloadedImages []KINDImageLoadStatus `json:"preloadedImages,omitempty"`
+
+	//+listType=map
+	//+listMapKey=name
+	// Represents the status of the addons in the order of the spec
+	// It is reset when the cluster is recreated, so the addons are applied to the new cluster.
+	Addons []KINDAddonStatus `json:"addons,omitempty"`
+
+	// Represents the host ports that were allocated to the cluster from the host port range
+	// of the controller by purpose: apiServer, http or https
+	// The allocations are kept when the cluster is recreated and released when it is deleted.
+	AllocatedPorts map[string]int32 `json:"allocatedPorts,omitempty"`
+
+	// Represents the differences between the spec and the running cluster
+	// It is empty if the running cluster matches the spec or the drift policy is Ignore
+	Drift []string `json:"drift,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -76,6 +650,9 @@ type KINDClusterStatus struct {
 //+kubebuilder:printcolumn:name="KubernetesVersion",type=string,JSONPath=`.spec.kubernetesVersion`,description="KubernetesVersion of the resource"
 //+kubebuilder:printcolumn:name="ClusterName",type=string,JSONPath=`.spec.clusterName`,description="ClusterName of the resource"
 //+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.ready`,description="Status of the resource"
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="Lifecycle phase of the resource"
+//+kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`,description="Number of Kubernetes nodes of the cluster"
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 //+kubebuilder:resource:path=kindclusters,shortName=kc
 
 // KINDCluster is the Schema for the kindclusters API
@@ -0,0 +1,528 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var kindclusterlog = logf.Log.WithName("kindcluster-resource")
+
+// The names of the kind clusters are also used in the names of the node containers,
+// so kind only accepts the names that match this expression
+var kindClusterNameRE = regexp.MustCompile(`^[a-z0-9.-]+$`)
+
+const (
+	// The maximum length of the cluster names
+	maxClusterNameLength = 64
+
+	// The length of the hash suffix of the shortened cluster names
+	clusterNameHashLength = 8
+)
+
+// The client that is used to find the other KINDClusters that use the same cluster name
+// and the image catalogs, it is set when the webhook is registered
+var kindclusterClient client.Reader
+
+// SetupWebhookWithManager registers the webhooks of KINDCluster in the manager
+func (r *KINDCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	kindclusterClient = mgr.GetClient()
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-k8s-io-v1alpha1-kindcluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster-k8s.io,resources=kindclusters,verbs=create;update,versions=v1alpha1,name=mkindcluster.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &KINDCluster{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *KINDCluster) Default() {
+	catalog, err := loadWebhookImageCatalog()
+
+	// The built-in images are still used to default the node image
+	if err != nil {
+		kindclusterlog.Error(err, "unable to load image catalog", "name", r.Name)
+	}
+
+	r.SetDefaults(catalog)
+}
+
+// SetDefaults sets the defaults of the spec, the node image is resolved from the image catalog
+func (r *KINDCluster) SetDefaults(catalog ImageCatalog) {
+	// The name of an object that is created with generateName is not known yet
+	if r.Spec.ClusterName == "" && r.Name != "" {
+		r.Spec.ClusterName = defaultClusterName(r.Namespace, r.Name)
+	}
+
+	if r.Spec.KubernetesVersion == "" {
+		r.Spec.KubernetesVersion = DefaultKubernetesVersion
+	}
+
+	// The image of the version is pinned into the spec, a pinned image of another version
+	// is replaced so that the cluster follows the changes of the version
+	if r.Spec.NodeImage == "" || catalog.IsVersionImage(r.Spec.NodeImage) {
+		if image, ok := catalog.Image(r.Spec.KubernetesVersion); ok {
+			r.Spec.NodeImage = image
+		}
+	}
+}
+
+// Get the default cluster name of a KINDCluster, the namespace is part of the name because
+// the cluster names must be unique across the namespaces
+// Both the namespace and the name can contain dashes, so a hash of the namespace and the name
+// is appended to tell apart, for example, "a-b/c" and "a/b-c". The names that are too long
+// are shortened before the hash.
+func defaultClusterName(namespace, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	hash := hex.EncodeToString(sum[:])[:clusterNameHashLength]
+
+	prefix := namespace + "-" + name
+
+	if len(prefix) > maxClusterNameLength-clusterNameHashLength-1 {
+		prefix = strings.TrimRight(prefix[:maxClusterNameLength-clusterNameHashLength-1], "-.")
+	}
+
+	return prefix + "-" + hash
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-k8s-io-v1alpha1-kindcluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster-k8s.io,resources=kindclusters,verbs=create;update,versions=v1alpha1,name=vkindcluster.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &KINDCluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *KINDCluster) ValidateCreate() error {
+	kindclusterlog.Info("validate create", "name", r.Name)
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validateKubernetesVersion()...)
+
+	// The cluster name of an object that is created with generateName is set later, when
+	// the name is known, and it is checked for uniqueness then
+	if r.Spec.ClusterName != "" {
+		allErrs = append(allErrs, r.validateClusterNameIsUnique()...)
+	}
+
+	return r.toAggregateError(allErrs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *KINDCluster) ValidateUpdate(old runtime.Object) error {
+	kindclusterlog.Info("validate update", "name", r.Name)
+
+	oldKindCluster, ok := old.(*KINDCluster)
+
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a KINDCluster but got a %T", old))
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validateImmutableFields(oldKindCluster)...)
+
+	// The cluster name of an object that was created with generateName is set once
+	if oldKindCluster.Spec.ClusterName == "" && r.Spec.ClusterName != "" {
+		allErrs = append(allErrs, r.validateClusterNameIsUnique()...)
+	}
+
+	// The version of an existing KINDCluster is not validated again, so that it can still
+	// be updated, for example deleted, after its version was removed from the catalogs
+	if r.Spec.KubernetesVersion != oldKindCluster.Spec.KubernetesVersion {
+		allErrs = append(allErrs, r.validateKubernetesVersion()...)
+	}
+
+	return r.toAggregateError(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *KINDCluster) ValidateDelete() error {
+	return nil
+}
+
+// Validate the fields of the spec and the constraints between them
+func (r *KINDCluster) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+
+	// The cluster name of an object that is created with generateName cannot be defaulted
+	// before the name is generated, the controller sets it after the object is created
+	if r.Spec.ClusterName == "" {
+		if r.Name != "" || r.GenerateName == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("clusterName"),
+				"must be set or defaulted from the name of the KINDCluster"))
+		}
+	} else if !kindClusterNameRE.MatchString(r.Spec.ClusterName) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("clusterName"), r.Spec.ClusterName,
+			fmt.Sprintf("must match the kind cluster name rule %s", kindClusterNameRE.String())))
+	}
+
+	if topology := r.Spec.Topology; topology != nil {
+		allErrs = append(allErrs, validateTopology(topology, specPath.Child("topology"))...)
+	}
+
+	for gate := range r.Spec.FeatureGates {
+		if gate == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("featureGates"), gate, "feature gate name must not be empty"))
+		}
+	}
+
+	for key := range r.Spec.RuntimeConfig {
+		if key == "" {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("runtimeConfig"), key, "API group version must not be empty"))
+		}
+	}
+
+	allErrs = append(allErrs, validateKubeadmConfigPatches(r.Spec.KubeadmConfigPatches,
+		specPath.Child("kubeadmConfigPatches"))...)
+
+	for i, patch := range r.Spec.ContainerdConfigPatches {
+		if _, err := toml.Load(patch); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("containerdConfigPatches").Index(i), patch,
+				fmt.Sprintf("must be a TOML document: %s", err)))
+		}
+	}
+
+	preloadImages := map[string]bool{}
+
+	for i, image := range r.Spec.PreloadImages {
+		imagePath := specPath.Child("preloadImages").Index(i)
+
+		if strings.TrimSpace(image) == "" {
+			allErrs = append(allErrs, field.Invalid(imagePath, image, "image must not be empty"))
+		} else if preloadImages[image] {
+			allErrs = append(allErrs, field.Duplicate(imagePath, image))
+		}
+
+		preloadImages[image] = true
+	}
+
+	addonNames := map[string]bool{}
+
+	for i, addon := range r.Spec.Addons {
+		addonPath := specPath.Child("addons").Index(i)
+
+		if addonNames[addon.Name] {
+			allErrs = append(allErrs, field.Duplicate(addonPath.Child("name"), addon.Name))
+		}
+
+		addonNames[addon.Name] = true
+
+		if (addon.ConfigMapRef == nil) == (addon.SecretRef == nil) {
+			allErrs = append(allErrs, field.Invalid(addonPath, addon.Name,
+				"exactly one of configMapRef and secretRef must be set"))
+		}
+	}
+
+	if r.Spec.KindConfig != "" && r.Spec.KindConfigRef != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("kindConfigRef"),
+			"kindConfig and kindConfigRef cannot be set together"))
+	}
+
+	if networking := r.Spec.Networking; networking != nil {
+		allErrs = append(allErrs, validateNetworking(networking, specPath.Child("networking"))...)
+	}
+
+	return allErrs
+}
+
+// Validate that no other KINDCluster, in any namespace, uses the same cluster name,
+// because all kind clusters share the node containers of the same host
+func (r *KINDCluster) validateClusterNameIsUnique() field.ErrorList {
+	if kindclusterClient == nil {
+		return nil
+	}
+
+	clusterNamePath := field.NewPath("spec", "clusterName")
+
+	var kindclusters KINDClusterList
+
+	if err := kindclusterClient.List(context.Background(), &kindclusters); err != nil {
+		return field.ErrorList{field.InternalError(clusterNamePath, err)}
+	}
+
+	for _, kindcluster := range kindclusters.Items {
+		if kindcluster.Namespace == r.Namespace && kindcluster.Name == r.Name {
+			continue
+		}
+
+		if kindcluster.Spec.ClusterName == r.Spec.ClusterName {
+			return field.ErrorList{field.Invalid(clusterNamePath, r.Spec.ClusterName,
+				fmt.Sprintf("is already used by KINDCluster %s/%s", kindcluster.Namespace, kindcluster.Name))}
+		}
+	}
+
+	return nil
+}
+
+// Validate that the kubernetes version is in the image catalog, a version that is not
+// in the catalog can only be used with an explicit node image
+func (r *KINDCluster) validateKubernetesVersion() field.ErrorList {
+	catalog, err := loadWebhookImageCatalog()
+
+	versionPath := field.NewPath("spec", "kubernetesVersion")
+
+	if err != nil {
+		return field.ErrorList{field.InternalError(versionPath, err)}
+	}
+
+	if _, ok := catalog.Resolve(r.Spec.KubernetesVersion); ok {
+		return nil
+	}
+
+	if r.Spec.NodeImage != "" && !catalog.IsVersionImage(r.Spec.NodeImage) {
+		return nil
+	}
+
+	return field.ErrorList{field.NotSupported(versionPath, r.Spec.KubernetesVersion, catalog.Versions())}
+}
+
+// Load the image catalog with the client of the webhook, only the built-in images are
+// returned if the client is not set
+func loadWebhookImageCatalog() (ImageCatalog, error) {
+	if kindclusterClient == nil {
+		return NewImageCatalog(nil), nil
+	}
+
+	catalog, err := LoadImageCatalog(context.Background(), kindclusterClient)
+
+	if err != nil {
+		return NewImageCatalog(nil), err
+	}
+
+	return catalog, nil
+}
+
+// Validate that the fields that cannot be changed on a running cluster are not changed
+// The kubernetes version and the topology can change, the drift policy decides how the
+// controller acts on them.
+func (r *KINDCluster) validateImmutableFields(old *KINDCluster) field.ErrorList {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+
+	// An empty cluster name is set by the controller after an object is created with generateName
+	if r.Spec.ClusterName != old.Spec.ClusterName && old.Spec.ClusterName != "" {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("clusterName"), "field is immutable"))
+	}
+
+	if !equality.Semantic.DeepEqual(r.Spec.Networking, old.Spec.Networking) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("networking"), "field is immutable"))
+	}
+
+	return allErrs
+}
+
+// Convert the validation errors to the error that is returned to the API server
+func (r *KINDCluster) toAggregateError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: KindOfKindCluster}, r.Name, allErrs)
+}
+
+// Validate the node topology of the cluster
+func validateTopology(topology *KINDClusterTopology, topologyPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if topology.ControlPlane.Replicas < 1 {
+		allErrs = append(allErrs, field.Invalid(topologyPath.Child("controlPlane", "replicas"),
+			topology.ControlPlane.Replicas, "the cluster must have at least one control-plane node"))
+	}
+
+	allErrs = append(allErrs, metav1validation.ValidateLabels(topology.ControlPlane.Labels,
+		topologyPath.Child("controlPlane", "labels"))...)
+
+	allErrs = append(allErrs, metav1validation.ValidateLabels(topology.Workers.Labels,
+		topologyPath.Child("workers", "labels"))...)
+
+	allErrs = append(allErrs, validateKubeadmConfigPatches(topology.ControlPlane.KubeadmConfigPatches,
+		topologyPath.Child("controlPlane", "kubeadmConfigPatches"))...)
+
+	allErrs = append(allErrs, validateKubeadmConfigPatches(topology.Workers.KubeadmConfigPatches,
+		topologyPath.Child("workers", "kubeadmConfigPatches"))...)
+
+	allErrs = append(allErrs, validateNodeTemplate(topology.ControlPlane.KINDNodeTemplate, topology.ControlPlane.Replicas,
+		topologyPath.Child("controlPlane"))...)
+
+	allErrs = append(allErrs, validateNodeTemplate(topology.Workers.KINDNodeTemplate, topology.Workers.Replicas,
+		topologyPath.Child("workers"))...)
+
+	return allErrs
+}
+
+// Validate the port mappings and the mounts of the nodes of a role, a host port can only be
+// published by a single node because the nodes of a role share the same host
+func validateNodeTemplate(template KINDNodeTemplate, replicas int32, templatePath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, mapping := range template.ExtraPortMappings {
+		mappingPath := templatePath.Child("extraPortMappings").Index(i)
+
+		if mapping.HostPort != 0 && replicas > 1 {
+			allErrs = append(allErrs, field.Invalid(mappingPath.Child("hostPort"), mapping.HostPort,
+				"a host port can only be mapped on a role with a single node"))
+		}
+
+		if mapping.ListenAddress != "" && net.ParseIP(mapping.ListenAddress) == nil {
+			allErrs = append(allErrs, field.Invalid(mappingPath.Child("listenAddress"), mapping.ListenAddress,
+				"must be a valid IP address"))
+		}
+	}
+
+	for i, mount := range template.ExtraMounts {
+		mountPath := templatePath.Child("extraMounts").Index(i)
+
+		if !path.IsAbs(mount.HostPath) {
+			allErrs = append(allErrs, field.Invalid(mountPath.Child("hostPath"), mount.HostPath, "must be an absolute path"))
+		}
+
+		if !path.IsAbs(mount.ContainerPath) {
+			allErrs = append(allErrs, field.Invalid(mountPath.Child("containerPath"), mount.ContainerPath,
+				"must be an absolute path"))
+		}
+	}
+
+	return allErrs
+}
+
+// Validate the kubeadm configuration patches, each patch must be a YAML object that
+// specifies the kind of the kubeadm configuration it patches, because kind matches the
+// patches to the configurations by their kinds
+func validateKubeadmConfigPatches(patches []string, patchesPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, patch := range patches {
+		var object map[string]interface{}
+
+		if err := yaml.Unmarshal([]byte(patch), &object); err != nil {
+			allErrs = append(allErrs, field.Invalid(patchesPath.Index(i), patch,
+				fmt.Sprintf("must be a YAML object: %s", err)))
+
+			continue
+		}
+
+		if kind, ok := object["kind"].(string); !ok || kind == "" {
+			allErrs = append(allErrs, field.Invalid(patchesPath.Index(i), patch,
+				"must specify the kind of the kubeadm configuration"))
+		}
+	}
+
+	return allErrs
+}
+
+// Validate the networking options of the cluster, the subnets must belong to the IP family
+// of the cluster and must not overlap
+func validateNetworking(networking *KINDClusterNetworking, networkingPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if networking.APIServerAddress != "" && net.ParseIP(networking.APIServerAddress) == nil {
+		allErrs = append(allErrs, field.Invalid(networkingPath.Child("apiServerAddress"),
+			networking.APIServerAddress, "must be a valid IP address"))
+	}
+
+	ipFamily := networking.IPFamily
+
+	// kind creates an IPv4 cluster if the IP family is not specified
+	if ipFamily == "" {
+		ipFamily = "ipv4"
+	}
+
+	podSubnets, errs := validateSubnets(networking.PodSubnet, ipFamily, networkingPath.Child("podSubnet"))
+	allErrs = append(allErrs, errs...)
+
+	serviceSubnets, errs := validateSubnets(networking.ServiceSubnet, ipFamily, networkingPath.Child("serviceSubnet"))
+	allErrs = append(allErrs, errs...)
+
+	for _, podSubnet := range podSubnets {
+		for _, serviceSubnet := range serviceSubnets {
+			if podSubnet.Contains(serviceSubnet.IP) || serviceSubnet.Contains(podSubnet.IP) {
+				allErrs = append(allErrs, field.Invalid(networkingPath.Child("serviceSubnet"), networking.ServiceSubnet,
+					fmt.Sprintf("must not overlap with the pod subnet %s", podSubnet.String())))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// Validate the comma separated CIDRs of a subnet, a single stack cluster has one CIDR of its
+// IP family and a dual stack cluster has one CIDR of each family
+// It returns the parsed CIDRs so that the subnets can be compared with each other.
+func validateSubnets(subnet, ipFamily string, subnetPath *field.Path) ([]*net.IPNet, field.ErrorList) {
+	if subnet == "" {
+		return nil, nil
+	}
+
+	var cidrs []*net.IPNet
+
+	for _, cidr := range strings.Split(subnet, ",") {
+		_, parsed, err := net.ParseCIDR(cidr)
+
+		if err != nil {
+			return nil, field.ErrorList{field.Invalid(subnetPath, subnet, fmt.Sprintf("%s is not a valid CIDR", cidr))}
+		}
+
+		cidrs = append(cidrs, parsed)
+	}
+
+	switch ipFamily {
+	case "dual":
+		if len(cidrs) != 2 || isIPv4CIDR(cidrs[0]) == isIPv4CIDR(cidrs[1]) {
+			return nil, field.ErrorList{field.Invalid(subnetPath, subnet,
+				"a dual stack cluster must have one IPv4 and one IPv6 CIDR")}
+		}
+	default:
+		if len(cidrs) != 1 {
+			return nil, field.ErrorList{field.Invalid(subnetPath, subnet, "a single stack cluster must have one CIDR")}
+		}
+
+		if isIPv4CIDR(cidrs[0]) != (ipFamily == "ipv4") {
+			return nil, field.ErrorList{field.Invalid(subnetPath, subnet,
+				fmt.Sprintf("must be a CIDR of the %s family", ipFamily))}
+		}
+	}
+
+	return cidrs, nil
+}
+
+// Check whether the CIDR is an IPv4 CIDR
+func isIPv4CIDR(cidr *net.IPNet) bool {
+	return cidr.IP.To4() != nil
+}
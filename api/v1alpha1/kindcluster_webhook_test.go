@@ -0,0 +1,368 @@
+package v1alpha1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestKINDCluster(namespace, name, clusterName string) *KINDCluster {
+	return &KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: KINDClusterSpec{
+			ClusterName:       clusterName,
+			KubernetesVersion: "1.21",
+		},
+	}
+}
+
+func Test_ValidateCreate(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		mutate  func(*KINDCluster)
+		wantErr bool
+	}{
+		{"valid", func(kc *KINDCluster) {}, false},
+		{"uppercase cluster name", func(kc *KINDCluster) { kc.Spec.ClusterName = "Test" }, true},
+		{"underscore in cluster name", func(kc *KINDCluster) { kc.Spec.ClusterName = "test_cluster" }, true},
+		{"empty cluster name", func(kc *KINDCluster) { kc.Spec.ClusterName = "" }, true},
+		{"empty cluster name with generateName", func(kc *KINDCluster) {
+			kc.Name, kc.GenerateName, kc.Spec.ClusterName = "", "test-", ""
+		}, false},
+		{"no control-plane node", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{}
+		}, true},
+		{"invalid node label", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{
+				ControlPlane: ControlPlaneTopology{Replicas: 1},
+				Workers: WorkerTopology{Replicas: 1, KINDNodeTemplate: KINDNodeTemplate{
+					Labels: map[string]string{"tier": "not a valid value"},
+				}},
+			}
+		}, true},
+		{"invalid API server address", func(kc *KINDCluster) {
+			kc.Spec.Networking = &KINDClusterNetworking{APIServerAddress: "1.2.3"}
+		}, true},
+		{"IPv6 subnet of an IPv4 cluster", func(kc *KINDCluster) {
+			kc.Spec.Networking = &KINDClusterNetworking{PodSubnet: "fd00:10:244::/56"}
+		}, true},
+		{"single subnet of a dual stack cluster", func(kc *KINDCluster) {
+			kc.Spec.Networking = &KINDClusterNetworking{IPFamily: "dual", PodSubnet: "10.244.0.0/16"}
+		}, true},
+		{"dual stack subnets", func(kc *KINDCluster) {
+			kc.Spec.Networking = &KINDClusterNetworking{
+				IPFamily:      "dual",
+				PodSubnet:     "10.244.0.0/16,fd00:10:244::/56",
+				ServiceSubnet: "10.96.0.0/16,fd00:10:96::/112",
+			}
+		}, false},
+		{"overlapping subnets", func(kc *KINDCluster) {
+			kc.Spec.Networking = &KINDClusterNetworking{PodSubnet: "10.0.0.0/8", ServiceSubnet: "10.96.0.0/16"}
+		}, true},
+		{"kubeadm config patch", func(kc *KINDCluster) {
+			kc.Spec.FeatureGates = map[string]bool{"EphemeralContainers": true}
+			kc.Spec.KubeadmConfigPatches = []string{"kind: ClusterConfiguration\napiServer:\n  extraArgs:\n    enable-admission-plugins: NodeRestriction\n"}
+		}, false},
+		{"kubeadm config patch that is not YAML", func(kc *KINDCluster) {
+			kc.Spec.KubeadmConfigPatches = []string{"kind: [ClusterConfiguration"}
+		}, true},
+		{"kubeadm config patch without kind", func(kc *KINDCluster) {
+			kc.Spec.KubeadmConfigPatches = []string{"apiServer:\n  extraArgs:\n    v: \"4\"\n"}
+		}, true},
+		{"invalid kubeadm config patch of the workers", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{
+				ControlPlane: ControlPlaneTopology{Replicas: 1},
+				Workers: WorkerTopology{Replicas: 1, KINDNodeTemplate: KINDNodeTemplate{
+					KubeadmConfigPatches: []string{"- kind: JoinConfiguration"},
+				}},
+			}
+		}, true},
+		{"host port of a single node", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{
+				ControlPlane: ControlPlaneTopology{Replicas: 1, KINDNodeTemplate: KINDNodeTemplate{
+					ExtraPortMappings: []PortMapping{{ContainerPort: 80, HostPort: 80, ListenAddress: "127.0.0.1"}},
+					ExtraMounts:       []Mount{{HostPath: "/etc/ssl/certs", ContainerPath: "/etc/ssl/certs", ReadOnly: true}},
+				}},
+			}
+		}, false},
+		{"host port of multiple nodes", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{
+				ControlPlane: ControlPlaneTopology{Replicas: 1},
+				Workers: WorkerTopology{Replicas: 2, KINDNodeTemplate: KINDNodeTemplate{
+					ExtraPortMappings: []PortMapping{{ContainerPort: 80, HostPort: 80}},
+				}},
+			}
+		}, true},
+		{"relative mount path", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{
+				ControlPlane: ControlPlaneTopology{Replicas: 1, KINDNodeTemplate: KINDNodeTemplate{
+					ExtraMounts: []Mount{{HostPath: "src", ContainerPath: "/src"}},
+				}},
+			}
+		}, true},
+		{"containerd config patch", func(kc *KINDCluster) {
+			kc.Spec.ContainerdConfigPatches = []string{"[plugins.\"io.containerd.grpc.v1.cri\".registry.mirrors.\"docker.io\"]\n  endpoint = [\"https://mirror.internal\"]"}
+		}, false},
+		{"containerd config patch that is not TOML", func(kc *KINDCluster) {
+			kc.Spec.ContainerdConfigPatches = []string{"[plugins.\"io.containerd.grpc.v1.cri\""}
+		}, true},
+		{"kind config and kind config reference", func(kc *KINDCluster) {
+			kc.Spec.KindConfig = "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\n"
+			kc.Spec.KindConfigRef = &KindConfigReference{Name: "test"}
+		}, true},
+		{"preload images", func(kc *KINDCluster) {
+			kc.Spec.PreloadImages = []string{"nginx:1.21", "/images/app.tar"}
+		}, false},
+		{"duplicate preload image", func(kc *KINDCluster) {
+			kc.Spec.PreloadImages = []string{"nginx:1.21", "nginx:1.21"}
+		}, true},
+		{"empty preload image", func(kc *KINDCluster) {
+			kc.Spec.PreloadImages = []string{" "}
+		}, true},
+		{"addons", func(kc *KINDCluster) {
+			kc.Spec.Addons = []KINDClusterAddon{
+				{Name: "cni", ConfigMapRef: &AddonSourceReference{Name: "calico"}},
+				{Name: "credentials", SecretRef: &AddonSourceReference{Name: "credentials", Key: "secret.yaml"}},
+			}
+		}, false},
+		{"duplicate addon", func(kc *KINDCluster) {
+			kc.Spec.Addons = []KINDClusterAddon{
+				{Name: "cni", ConfigMapRef: &AddonSourceReference{Name: "calico"}},
+				{Name: "cni", ConfigMapRef: &AddonSourceReference{Name: "cilium"}},
+			}
+		}, true},
+		{"addon without source", func(kc *KINDCluster) {
+			kc.Spec.Addons = []KINDClusterAddon{{Name: "cni"}}
+		}, true},
+		{"addon with two sources", func(kc *KINDCluster) {
+			kc.Spec.Addons = []KINDClusterAddon{{Name: "cni", ConfigMapRef: &AddonSourceReference{Name: "calico"},
+				SecretRef: &AddonSourceReference{Name: "calico"}}}
+		}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := newTestKINDCluster("default", "test", "test")
+			tc.mutate(kindcluster)
+
+			if err := kindcluster.ValidateCreate(); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ValidateCreateClusterNameIsUnique(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(AddToScheme(testScheme))
+
+	kindclusterClient = fake.NewFakeClientWithScheme(testScheme, newTestKINDCluster("team-a", "test", "test"))
+	defer func() { kindclusterClient = nil }()
+
+	var testCases = []struct {
+		name        string
+		namespace   string
+		clusterName string
+		wantErr     bool
+	}{
+		{"same cluster name in another namespace", "team-b", "test", true},
+		{"another cluster name", "team-b", "test-b", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := newTestKINDCluster(tc.namespace, "test", tc.clusterName)
+
+			if err := kindcluster.ValidateCreate(); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ValidateUpdate(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		mutate  func(*KINDCluster)
+		wantErr bool
+	}{
+		{"kubernetes version", func(kc *KINDCluster) { kc.Spec.KubernetesVersion = "1.20" }, false},
+		{"topology", func(kc *KINDCluster) {
+			kc.Spec.Topology = &KINDClusterTopology{ControlPlane: ControlPlaneTopology{Replicas: 3}}
+		}, false},
+		{"cluster name", func(kc *KINDCluster) { kc.Spec.ClusterName = "test-b" }, true},
+		{"networking", func(kc *KINDCluster) {
+			kc.Spec.Networking = &KINDClusterNetworking{KubeProxyMode: "ipvs"}
+		}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			old := newTestKINDCluster("default", "test", "test")
+			kindcluster := old.DeepCopy()
+			tc.mutate(kindcluster)
+
+			if err := kindcluster.ValidateUpdate(old); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ValidateGenerateName(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(AddToScheme(testScheme))
+
+	kindclusterClient = fake.NewFakeClientWithScheme(testScheme, newTestKINDCluster("default", "other", "default-test-x7k2p"))
+	defer func() { kindclusterClient = nil }()
+
+	// The cluster name cannot be defaulted before the name is generated
+	created := newTestKINDCluster("default", "", "")
+	created.GenerateName = "test-"
+
+	created.Default()
+
+	if err := created.ValidateCreate(); err != nil || created.Spec.ClusterName != "" {
+		t.Fatalf("ValidateCreate() error = %v, clusterName = %q, want no error and an empty cluster name",
+			err, created.Spec.ClusterName)
+	}
+
+	// The controller sets the cluster name after the name is generated
+	old := created.DeepCopy()
+	old.Name = "test-abcde"
+
+	var testCases = []struct {
+		name        string
+		clusterName string
+		wantErr     bool
+	}{
+		{"default cluster name", defaultClusterName("default", "test-abcde"), false},
+		{"cluster name of another KINDCluster", "default-test-x7k2p", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := old.DeepCopy()
+			kindcluster.Spec.ClusterName = tc.clusterName
+
+			if err := kindcluster.ValidateUpdate(old); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Default(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		spec        KINDClusterSpec
+		clusterName string
+		version     string
+		nodeImage   string
+	}{
+		{"empty spec", KINDClusterSpec{}, "default-test-" + hashSuffix("default", "test"), DefaultKubernetesVersion,
+			builtinImage(DefaultKubernetesVersion)},
+		{"explicit cluster name and version", KINDClusterSpec{ClusterName: "test", KubernetesVersion: "1.20"}, "test", "1.20",
+			builtinImage("1.20")},
+		{"pinned image of another version", KINDClusterSpec{KubernetesVersion: "1.20", NodeImage: builtinImage("1.21")},
+			"default-test-" + hashSuffix("default", "test"), "1.20", builtinImage("1.20")},
+		{"custom image", KINDClusterSpec{KubernetesVersion: "1.20", NodeImage: "registry.local/node:custom"},
+			"default-test-" + hashSuffix("default", "test"), "1.20", "registry.local/node:custom"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := newTestKINDCluster("default", "test", "")
+			kindcluster.Spec = tc.spec
+
+			kindcluster.Default()
+
+			if got := kindcluster.Spec; got.ClusterName != tc.clusterName || got.KubernetesVersion != tc.version ||
+				got.NodeImage != tc.nodeImage {
+				t.Errorf("Default() spec = %+v, want clusterName %s, version %s and nodeImage %s",
+					got, tc.clusterName, tc.version, tc.nodeImage)
+			}
+		})
+	}
+}
+
+func Test_DefaultClusterName(t *testing.T) {
+	longName := strings.Repeat("a", 60)
+
+	var testCases = []struct {
+		namespace string
+		name      string
+		want      string
+	}{
+		{"default", "test", "default-test-" + hashSuffix("default", "test")},
+		{"a-b", "c", "a-b-c-" + hashSuffix("a-b", "c")},
+		{"a", "b-c", "a-b-c-" + hashSuffix("a", "b-c")},
+		{"team-a", longName, "team-a-" + longName[:48] + "-" + hashSuffix("team-a", longName)},
+		{"team-b", longName, "team-b-" + longName[:48] + "-" + hashSuffix("team-b", longName)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.namespace+"/"+tc.name, func(t *testing.T) {
+			got := defaultClusterName(tc.namespace, tc.name)
+
+			if got != tc.want || len(got) > maxClusterNameLength || !kindClusterNameRE.MatchString(got) {
+				t.Errorf("defaultClusterName() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func hashSuffix(namespace, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+
+	return hex.EncodeToString(sum[:])[:clusterNameHashLength]
+}
+
+func builtinImage(version string) string {
+	image, _ := NewImageCatalog(nil).Image(version)
+
+	return image
+}
+
+func Test_ValidateCreateKubernetesVersion(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(AddToScheme(testScheme))
+
+	kindclusterClient = fake.NewFakeClientWithScheme(testScheme, &KINDImageCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: KINDImageCatalogSpec{Images: []KINDImage{
+			{Version: "1.23", Image: "kindest/node:v1.23.0"},
+		}},
+	})
+	defer func() { kindclusterClient = nil }()
+
+	var testCases = []struct {
+		name      string
+		version   string
+		nodeImage string
+		wantErr   bool
+	}{
+		{"built-in version", "1.21", "", false},
+		{"version of a catalog", "1.23", "", false},
+		{"unknown version", "1.99", "", true},
+		{"patch version of the minor version image", "v1.21.1", "", false},
+		{"unknown patch version", "v1.21.999", "", true},
+		{"unknown patch version with a custom image", "v1.21.999", "registry.local/node:v1.21.999", false},
+		{"unknown version with a custom image", "1.99", "registry.local/node:v1.99.0", false},
+		{"unknown version with an image of another version", "1.99", builtinImage("1.21"), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := newTestKINDCluster("default", "test", "test")
+			kindcluster.Spec.KubernetesVersion = tc.version
+			kindcluster.Spec.NodeImage = tc.nodeImage
+
+			if err := kindcluster.ValidateCreate(); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var KindOfKindImageCatalog = "KINDImageCatalog"
+
+// KINDImageCatalogSpec defines the node images of the Kubernetes versions
+type KINDImageCatalogSpec struct {
+	//+listType=map
+	//+listMapKey=version
+	// Specifies the node images of the Kubernetes versions
+	Images []KINDImage `json:"images,omitempty"`
+}
+
+// KINDImage defines the node image of a Kubernetes version
+type KINDImage struct {
+	//+kubebuilder:validation:Pattern=`^[0-9]+\.[0-9]+(\.[0-9]+)?$`
+	// Specifies the Kubernetes version, a minor version such as 1.21 or a patch version
+	// such as 1.21.14
+	Version string `json:"version"`
+
+	//+kubebuilder:validation:MinLength=1
+	// Specifies the node image of the version, for example kindest/node:v1.21.1
+	Image string `json:"image"`
+
+	//+kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// Specifies the digest of the node image, the image is pinned by this digest
+	Digest string `json:"digest,omitempty"`
+
+	// Specifies whether the version is deprecated, the KINDClusters of a deprecated
+	// version are still created but it is reported in their status
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// Reference returns the reference of the node image, including the digest if it is specified
+func (i KINDImage) Reference() string {
+	if i.Digest == "" {
+		return i.Image
+	}
+
+	return i.Image + "@" + i.Digest
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=kindimagecatalogs,scope=Cluster,shortName=kic
+
+// KINDImageCatalog is the Schema for the kindimagecatalogs API
+// The catalogs add node images to the built-in images of the controller or replace them,
+// all catalogs are merged in the order of their names.
+type KINDImageCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KINDImageCatalogSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KINDImageCatalogList contains a list of KINDImageCatalog
+type KINDImageCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KINDImageCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KINDImageCatalog{}, &KINDImageCatalogList{})
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var KindOfKindMachine = "KINDMachine"
+
+// NodeProvisionedCondition reports whether the node of the KINDMachine joined the cluster
+const NodeProvisionedCondition = "NodeProvisioned"
+
+// KINDMachineSpec defines the desired state of KINDMachine
+type KINDMachineSpec struct {
+	// Specifies the name of the KINDCluster in the same namespace that the node joins
+	// If it is not specified, the KINDCluster is found through the owner Cluster API Machine
+	KINDClusterName string `json:"kindClusterName,omitempty"`
+
+	//+kubebuilder:validation:Enum=control-plane;worker
+	//+kubebuilder:default=worker
+	// Specifies the role of the node
+	Role string `json:"role,omitempty"`
+
+	// Specifies the node image, if it is not specified the image of the KINDCluster is used
+	Image string `json:"image,omitempty"`
+
+	// Specifies the labels that will be added to the node, the keys and the values must be
+	// valid Kubernetes labels
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Specifies the host paths that will be mounted into the node container
+	ExtraMounts []Mount `json:"extraMounts,omitempty"`
+
+	// Represents the provider ID of the node, it is set by the controller
+	// when the node joins the cluster, as required by the Cluster API contract
+	ProviderID *string `json:"providerID,omitempty"`
+}
+
+// Mount defines a host path that is mounted into a node container
+type Mount struct {
+	// Specifies the path on the host, it must be an absolute path
+	//+kubebuilder:validation:Pattern=`^/`
+	HostPath string `json:"hostPath"`
+
+	// Specifies the path in the node container, it must be an absolute path
+	//+kubebuilder:validation:Pattern=`^/`
+	ContainerPath string `json:"containerPath"`
+
+	// Specifies whether the mount is read-only
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// MachineAddress defines an address of the node
+type MachineAddress struct {
+	// Represents the type of the address: Hostname, InternalIP or ExternalIP
+	Type string `json:"type"`
+
+	// Represents the address
+	Address string `json:"address"`
+}
+
+// KINDMachineStatus defines the observed state of KINDMachine
+type KINDMachineStatus struct {
+	// Represents the state of the node
+	// true if the node container exists and joined the cluster
+	Ready bool `json:"ready,omitempty"`
+
+	// Represents the name of the node container
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Represents the addresses of the node
+	Addresses []MachineAddress `json:"addresses,omitempty"`
+
+	// Represents the operation that is running on the node in the background
+	Operation *KINDClusterOperation `json:"operation,omitempty"`
+
+	// Represents the terminal failure reason of the node in a format
+	// that Cluster API can interpret, for example CreateError
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// Represents the failure reason of the node creation
+	FailureMessage string `json:"failureMessage,omitempty"`
+
+	// Represents the generation of the spec that was last reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	//+listType=map
+	//+listMapKey=type
+	// Represents the current status conditions of the node
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Represents the last changes of the status conditions
+	// Only the most recent events are kept.
+	History []KINDStatusEvent `json:"history,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="KINDCluster",type=string,JSONPath=`.spec.kindClusterName`,description="KINDCluster of the resource"
+//+kubebuilder:printcolumn:name="Role",type=string,JSONPath=`.spec.role`,description="Role of the node"
+//+kubebuilder:printcolumn:name="ProviderID",type=string,JSONPath=`.spec.providerID`,description="Provider ID of the node"
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.ready`,description="Status of the resource"
+//+kubebuilder:resource:path=kindmachines,shortName=km
+
+// KINDMachine is the Schema for the kindmachines API
+// It represents a single node container of a KIND Cluster
+type KINDMachine struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KINDMachineSpec   `json:"spec,omitempty"`
+	Status KINDMachineStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KINDMachineList contains a list of KINDMachine
+type KINDMachineList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KINDMachine `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KINDMachine{}, &KINDMachineList{})
+}
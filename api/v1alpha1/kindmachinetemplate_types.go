@@ -0,0 +1,58 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KINDMachineTemplateSpec defines the desired state of KINDMachineTemplate
+type KINDMachineTemplateSpec struct {
+	// Specifies the template that the KINDMachines are created from
+	Template KINDMachineTemplateResource `json:"template"`
+}
+
+// KINDMachineTemplateResource describes the data needed to create a KINDMachine from a template
+type KINDMachineTemplateResource struct {
+	// Specifies the spec of the KINDMachines
+	Spec KINDMachineSpec `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=kindmachinetemplates,shortName=kmt
+
+// KINDMachineTemplate is the Schema for the kindmachinetemplates API
+// Cluster API MachineDeployments and MachineSets create KINDMachines from it
+type KINDMachineTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KINDMachineTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KINDMachineTemplateList contains a list of KINDMachineTemplate
+type KINDMachineTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KINDMachineTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KINDMachineTemplate{}, &KINDMachineTemplateList{})
+}
@@ -21,15 +21,131 @@ limitations under the License.
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIEndpoint) DeepCopyInto(out *APIEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIEndpoint.
+func (in *APIEndpoint) DeepCopy() *APIEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(APIEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonSourceReference) DeepCopyInto(out *AddonSourceReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonSourceReference.
+func (in *AddonSourceReference) DeepCopy() *AddonSourceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(AddonSourceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneTopology) DeepCopyInto(out *ControlPlaneTopology) {
+	*out = *in
+	in.KINDNodeTemplate.DeepCopyInto(&out.KINDNodeTemplate)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneTopology.
+func (in *ControlPlaneTopology) DeepCopy() *ControlPlaneTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneTopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureDomainSpec) DeepCopyInto(out *FailureDomainSpec) {
+	*out = *in
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomainSpec.
+func (in *FailureDomainSpec) DeepCopy() *FailureDomainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureDomainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in ImageCatalog) DeepCopyInto(out *ImageCatalog) {
+	{
+		in := &in
+		*out = make(ImageCatalog, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCatalog.
+func (in ImageCatalog) DeepCopy() ImageCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCatalog)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDAddonStatus) DeepCopyInto(out *KINDAddonStatus) {
+	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDAddonStatus.
+func (in *KINDAddonStatus) DeepCopy() *KINDAddonStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDAddonStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KINDCluster) DeepCopyInto(out *KINDCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -51,6 +167,46 @@ func (in *KINDCluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDClusterAddon) DeepCopyInto(out *KINDClusterAddon) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(AddonSourceReference)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(AddonSourceReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterAddon.
+func (in *KINDClusterAddon) DeepCopy() *KINDClusterAddon {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDClusterAddon)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDClusterIngress) DeepCopyInto(out *KINDClusterIngress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterIngress.
+func (in *KINDClusterIngress) DeepCopy() *KINDClusterIngress {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDClusterIngress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KINDClusterList) DeepCopyInto(out *KINDClusterList) {
 	*out = *in
@@ -83,9 +239,122 @@ func (in *KINDClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDClusterNetworking) DeepCopyInto(out *KINDClusterNetworking) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterNetworking.
+func (in *KINDClusterNetworking) DeepCopy() *KINDClusterNetworking {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDClusterNetworking)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDClusterOperation) DeepCopyInto(out *KINDClusterOperation) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterOperation.
+func (in *KINDClusterOperation) DeepCopy() *KINDClusterOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDClusterOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDClusterRegistry) DeepCopyInto(out *KINDClusterRegistry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterRegistry.
+func (in *KINDClusterRegistry) DeepCopy() *KINDClusterRegistry {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDClusterRegistry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KINDClusterSpec) DeepCopyInto(out *KINDClusterSpec) {
 	*out = *in
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(KINDClusterTopology)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(KINDClusterNetworking)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(KINDClusterIngress)
+		**out = **in
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(KINDClusterRegistry)
+		**out = **in
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RuntimeConfig != nil {
+		in, out := &in.RuntimeConfig, &out.RuntimeConfig
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KubeadmConfigPatches != nil {
+		in, out := &in.KubeadmConfigPatches, &out.KubeadmConfigPatches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ContainerdConfigPatches != nil {
+		in, out := &in.ContainerdConfigPatches, &out.ContainerdConfigPatches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistryAuth != nil {
+		in, out := &in.RegistryAuth, &out.RegistryAuth
+		*out = new(RegistryAuthReference)
+		**out = **in
+	}
+	if in.PreloadImages != nil {
+		in, out := &in.PreloadImages, &out.PreloadImages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Addons != nil {
+		in, out := &in.Addons, &out.Addons
+		*out = make([]KINDClusterAddon, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KindConfigRef != nil {
+		in, out := &in.KindConfigRef, &out.KindConfigRef
+		*out = new(KindConfigReference)
+		**out = **in
+	}
+	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterSpec.
@@ -106,13 +375,85 @@ func (in *KINDClusterStatus) DeepCopyInto(out *KINDClusterStatus) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Operation != nil {
+		in, out := &in.Operation, &out.Operation
+		*out = new(KINDClusterOperation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make(map[string]FailureDomainSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]KindClusterCondition, len(*in))
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]KINDStatusEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]KINDNodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeImages != nil {
+		in, out := &in.NodeImages, &out.NodeImages
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(KINDClusterNetworking)
+		**out = **in
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]KINDPortStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(KINDRegistryStatus)
+		**out = **in
+	}
+	if in.PreloadedImages != nil {
+		in, out := &in.PreloadedImages, &out.PreloadedImages
+		*out = make([]KINDImageLoadStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Addons != nil {
+		in, out := &in.Addons, &out.Addons
+		*out = make([]KINDAddonStatus, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AllocatedPorts != nil {
+		in, out := &in.AllocatedPorts, &out.AllocatedPorts
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterStatus.
@@ -126,17 +467,536 @@ func (in *KINDClusterStatus) DeepCopy() *KINDClusterStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KindClusterCondition) DeepCopyInto(out *KindClusterCondition) {
+func (in *KINDClusterTopology) DeepCopyInto(out *KINDClusterTopology) {
 	*out = *in
-	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	in.ControlPlane.DeepCopyInto(&out.ControlPlane)
+	in.Workers.DeepCopyInto(&out.Workers)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDClusterTopology.
+func (in *KINDClusterTopology) DeepCopy() *KINDClusterTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDClusterTopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDImage) DeepCopyInto(out *KINDImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDImage.
+func (in *KINDImage) DeepCopy() *KINDImage {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDImageCatalog) DeepCopyInto(out *KINDImageCatalog) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDImageCatalog.
+func (in *KINDImageCatalog) DeepCopy() *KINDImageCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDImageCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KINDImageCatalog) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDImageCatalogList) DeepCopyInto(out *KINDImageCatalogList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KINDImageCatalog, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDImageCatalogList.
+func (in *KINDImageCatalogList) DeepCopy() *KINDImageCatalogList {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDImageCatalogList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KINDImageCatalogList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDImageCatalogSpec) DeepCopyInto(out *KINDImageCatalogSpec) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]KINDImage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDImageCatalogSpec.
+func (in *KINDImageCatalogSpec) DeepCopy() *KINDImageCatalogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDImageCatalogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDImageLoadStatus) DeepCopyInto(out *KINDImageLoadStatus) {
+	*out = *in
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDImageLoadStatus.
+func (in *KINDImageLoadStatus) DeepCopy() *KINDImageLoadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDImageLoadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachine) DeepCopyInto(out *KINDMachine) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachine.
+func (in *KINDMachine) DeepCopy() *KINDMachine {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KINDMachine) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineList) DeepCopyInto(out *KINDMachineList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KINDMachine, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineList.
+func (in *KINDMachineList) DeepCopy() *KINDMachineList {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KINDMachineList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineSpec) DeepCopyInto(out *KINDMachineSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExtraMounts != nil {
+		in, out := &in.ExtraMounts, &out.ExtraMounts
+		*out = make([]Mount, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProviderID != nil {
+		in, out := &in.ProviderID, &out.ProviderID
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineSpec.
+func (in *KINDMachineSpec) DeepCopy() *KINDMachineSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineStatus) DeepCopyInto(out *KINDMachineStatus) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]MachineAddress, len(*in))
+		copy(*out, *in)
+	}
+	if in.Operation != nil {
+		in, out := &in.Operation, &out.Operation
+		*out = new(KINDClusterOperation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]KINDStatusEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineStatus.
+func (in *KINDMachineStatus) DeepCopy() *KINDMachineStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineTemplate) DeepCopyInto(out *KINDMachineTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineTemplate.
+func (in *KINDMachineTemplate) DeepCopy() *KINDMachineTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KINDMachineTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineTemplateList) DeepCopyInto(out *KINDMachineTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KINDMachineTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineTemplateList.
+func (in *KINDMachineTemplateList) DeepCopy() *KINDMachineTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KINDMachineTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineTemplateResource) DeepCopyInto(out *KINDMachineTemplateResource) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineTemplateResource.
+func (in *KINDMachineTemplateResource) DeepCopy() *KINDMachineTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDMachineTemplateSpec) DeepCopyInto(out *KINDMachineTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDMachineTemplateSpec.
+func (in *KINDMachineTemplateSpec) DeepCopy() *KINDMachineTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDMachineTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDNodeStatus) DeepCopyInto(out *KINDNodeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDNodeStatus.
+func (in *KINDNodeStatus) DeepCopy() *KINDNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDNodeTemplate) DeepCopyInto(out *KINDNodeTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KubeadmConfigPatches != nil {
+		in, out := &in.KubeadmConfigPatches, &out.KubeadmConfigPatches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraPortMappings != nil {
+		in, out := &in.ExtraPortMappings, &out.ExtraPortMappings
+		*out = make([]PortMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraMounts != nil {
+		in, out := &in.ExtraMounts, &out.ExtraMounts
+		*out = make([]Mount, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDNodeTemplate.
+func (in *KINDNodeTemplate) DeepCopy() *KINDNodeTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDNodeTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDPortStatus) DeepCopyInto(out *KINDPortStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDPortStatus.
+func (in *KINDPortStatus) DeepCopy() *KINDPortStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDPortStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDRegistryStatus) DeepCopyInto(out *KINDRegistryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDRegistryStatus.
+func (in *KINDRegistryStatus) DeepCopy() *KINDRegistryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDRegistryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KINDStatusEvent) DeepCopyInto(out *KINDStatusEvent) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KINDStatusEvent.
+func (in *KINDStatusEvent) DeepCopy() *KINDStatusEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(KINDStatusEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KindConfigReference) DeepCopyInto(out *KindConfigReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindConfigReference.
+func (in *KindConfigReference) DeepCopy() *KindConfigReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KindConfigReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineAddress) DeepCopyInto(out *MachineAddress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineAddress.
+func (in *MachineAddress) DeepCopy() *MachineAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Mount) DeepCopyInto(out *Mount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Mount.
+func (in *Mount) DeepCopy() *Mount {
+	if in == nil {
+		return nil
+	}
+	out := new(Mount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortMapping) DeepCopyInto(out *PortMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortMapping.
+func (in *PortMapping) DeepCopy() *PortMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(PortMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryAuthReference) DeepCopyInto(out *RegistryAuthReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryAuthReference.
+func (in *RegistryAuthReference) DeepCopy() *RegistryAuthReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryAuthReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerTopology) DeepCopyInto(out *WorkerTopology) {
+	*out = *in
+	in.KINDNodeTemplate.DeepCopyInto(&out.KINDNodeTemplate)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindClusterCondition.
-func (in *KindClusterCondition) DeepCopy() *KindClusterCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerTopology.
+func (in *WorkerTopology) DeepCopy() *WorkerTopology {
 	if in == nil {
 		return nil
 	}
-	out := new(KindClusterCondition)
+	out := new(WorkerTopology)
 	in.DeepCopyInto(out)
 	return out
 }
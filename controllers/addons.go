@@ -0,0 +1,387 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Keys for logs
+const addonNameKey = "addon"
+
+// addonError is an error of an addon that is reported in its Applied condition
+type addonError struct {
+	reason  string
+	message string
+}
+
+func (e *addonError) Error() string {
+	return e.message
+}
+
+// addonManifest is a document of the manifests of an addon
+type addonManifest struct {
+	key  string
+	data []byte
+}
+
+// Read the manifests of the addon from its ConfigMap or Secret, the keys are sorted by name
+// if the key is not specified
+func getAddonManifests(ctx context.Context, c client.Reader, namespace string,
+	addon infrastructurev1alpha1.KINDClusterAddon) ([]addonManifest, error) {
+	var data map[string][]byte
+
+	var ref *infrastructurev1alpha1.AddonSourceReference
+
+	var sourceKind string
+
+	key := types.NamespacedName{Namespace: namespace}
+
+	if addon.SecretRef != nil {
+		ref, sourceKind = addon.SecretRef, "Secret"
+		key.Name = ref.Name
+
+		var secret corev1.Secret
+
+		if err := c.Get(ctx, key, &secret); err != nil {
+			return nil, getAddonSourceError(err, sourceKind, ref.Name)
+		}
+
+		data = secret.Data
+	} else if addon.ConfigMapRef != nil {
+		ref, sourceKind = addon.ConfigMapRef, "ConfigMap"
+		key.Name = ref.Name
+
+		var configMap corev1.ConfigMap
+
+		if err := c.Get(ctx, key, &configMap); err != nil {
+			return nil, getAddonSourceError(err, sourceKind, ref.Name)
+		}
+
+		data = map[string][]byte{}
+
+		for k, v := range configMap.Data {
+			data[k] = []byte(v)
+		}
+	} else {
+		return nil, &addonError{reason: reasonAddonSourceNotFound, message: "addon does not have a source"}
+	}
+
+	if ref.Key != "" {
+		manifest, ok := data[ref.Key]
+
+		if !ok {
+			return nil, &addonError{
+				reason:  reasonAddonSourceNotFound,
+				message: fmt.Sprintf("%s %s does not have the key %s", sourceKind, ref.Name, ref.Key),
+			}
+		}
+
+		return []addonManifest{{key: ref.Key, data: manifest}}, nil
+	}
+
+	manifests := make([]addonManifest, 0, len(data))
+
+	for k, v := range data {
+		manifests = append(manifests, addonManifest{key: k, data: v})
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].key < manifests[j].key
+	})
+
+	return manifests, nil
+}
+
+// Convert the error of reading the source of an addon, a missing source is reported in the
+// condition of the addon and the other errors are returned
+func getAddonSourceError(err error, sourceKind, name string) error {
+	if k8serrors.IsNotFound(err) {
+		return &addonError{reason: reasonAddonSourceNotFound, message: fmt.Sprintf("%s %s is not found", sourceKind, name)}
+	}
+
+	return err
+}
+
+// Get the hash of the manifests of an addon, it changes when a key or a document changes
+func hashAddonManifests(manifests []addonManifest) string {
+	hash := sha256.New()
+
+	for _, manifest := range manifests {
+		hash.Write([]byte(manifest.key))
+		hash.Write([]byte{0})
+		hash.Write(manifest.data)
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Parse the YAML or JSON documents of the manifest into objects, the items of the lists
+// are returned as separate objects and the empty documents are skipped
+func parseAddonManifest(data []byte) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	var objects []*unstructured.Unstructured
+
+	for {
+		var document map[string]interface{}
+
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			return objects, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(document) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: document}
+
+		if obj.IsList() {
+			if err := obj.EachListItem(func(item runtime.Object) error {
+				objects = append(objects, item.(*unstructured.Unstructured))
+
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		objects = append(objects, obj)
+	}
+}
+
+// Parse the manifests of an addon, the objects must have an apiVersion, a kind and a name
+// The parse errors of a Secret are not reported, because they can contain its data.
+func parseAddonManifests(addon infrastructurev1alpha1.KINDClusterAddon,
+	manifests []addonManifest) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+
+	for _, manifest := range manifests {
+		parsed, err := parseAddonManifest(manifest.data)
+
+		if err != nil {
+			message := fmt.Sprintf("key %s is not a valid YAML manifest", manifest.key)
+
+			if addon.SecretRef == nil {
+				message = fmt.Sprintf("%s: %s", message, err)
+			}
+
+			return nil, &addonError{reason: reasonInvalidAddonManifest, message: message}
+		}
+
+		for _, obj := range parsed {
+			if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+				return nil, &addonError{
+					reason:  reasonInvalidAddonManifest,
+					message: fmt.Sprintf("key %s has an object without apiVersion, kind or name", manifest.key),
+				}
+			}
+		}
+
+		objects = append(objects, parsed...)
+	}
+
+	return objects, nil
+}
+
+// Apply the addons of the KINDCluster instance into the cluster in their order and report
+// their status, an addon is applied again only when its manifests change
+// The addons after a failed addon are not applied, because they can depend on it. The
+// connection to the cluster is opened only if an addon needs to be applied. It returns true
+// if an addon failed, the failed addon is retried in the next reconciliation.
+func (r *KINDClusterReconciler) applyAddons(ctx context.Context, kindcluster *infrastructurev1alpha1.KINDCluster,
+	kubeconfig []byte, log logr.Logger) (bool, error) {
+	clusterName := kindcluster.Spec.ClusterName
+
+	previous := map[string]infrastructurev1alpha1.KINDAddonStatus{}
+
+	for _, status := range kindcluster.Status.Addons {
+		previous[status.Name] = status
+	}
+
+	var statuses []infrastructurev1alpha1.KINDAddonStatus
+
+	var workload workloadCluster
+
+	var failed *infrastructurev1alpha1.KINDAddonStatus
+
+	for _, addon := range kindcluster.Spec.Addons {
+		status := previous[addon.Name]
+		status.Name = addon.Name
+
+		// Copy the conditions, so the status of the previous reconciliation is not changed
+		status.Conditions = append([]metav1.Condition(nil), status.Conditions...)
+
+		if failed != nil {
+			setAddonCondition(kindcluster, &status, metav1.ConditionFalse, reasonWaitingForAddon,
+				fmt.Sprintf("Waiting for addon %s to be applied", failed.Name))
+			statuses = append(statuses, status)
+
+			continue
+		}
+
+		applied, err := r.applyAddon(ctx, kindcluster, addon, &status, &workload, kubeconfig)
+
+		var applyError *addonError
+
+		if errors.As(err, &applyError) {
+			log.Info("Addon cannot be applied", clusterNameKey, clusterName, addonNameKey, addon.Name,
+				"reason", applyError.reason)
+
+			setAddonCondition(kindcluster, &status, metav1.ConditionFalse, applyError.reason, applyError.message)
+		} else if err != nil {
+			return false, err
+		} else if applied {
+			log.Info("Addon is applied into cluster", clusterNameKey, clusterName, addonNameKey, addon.Name)
+		}
+
+		statuses = append(statuses, status)
+
+		if applyError != nil {
+			failed = &statuses[len(statuses)-1]
+		}
+	}
+
+	kindcluster.Status.Addons = statuses
+
+	setAddonsAppliedCondition(kindcluster, failed)
+
+	return failed != nil, nil
+}
+
+// Apply the addon if its manifests changed since it was last applied or it is not applied,
+// the connection to the cluster is opened on the first application
+// It returns true if the addon was applied.
+func (r *KINDClusterReconciler) applyAddon(ctx context.Context, kindcluster *infrastructurev1alpha1.KINDCluster,
+	addon infrastructurev1alpha1.KINDClusterAddon, status *infrastructurev1alpha1.KINDAddonStatus,
+	workload *workloadCluster, kubeconfig []byte) (bool, error) {
+	manifests, err := getAddonManifests(ctx, r.Client, kindcluster.Namespace, addon)
+
+	if err != nil {
+		return false, err
+	}
+
+	hash := hashAddonManifests(manifests)
+
+	if hash == status.AppliedHash && meta.IsStatusConditionTrue(status.Conditions, infrastructurev1alpha1.AddonAppliedCondition) {
+		return false, nil
+	}
+
+	objects, err := parseAddonManifests(addon, manifests)
+
+	if err != nil {
+		return false, err
+	}
+
+	if *workload == nil {
+		if *workload, err = r.getWorkloadCluster(kindcluster.Spec.ClusterName, kubeconfig); err != nil {
+			return false, &addonError{
+				reason:  reasonAddonApplyFailed,
+				message: fmt.Sprintf("unable to connect to cluster: %s", err),
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		if err := (*workload).Apply(ctx, obj); err != nil {
+			return false, &addonError{
+				reason:  reasonAddonApplyFailed,
+				message: fmt.Sprintf("unable to apply %s %s: %s", obj.GetKind(), getObjectName(obj), err),
+			}
+		}
+	}
+
+	now := metav1.Now()
+
+	status.AppliedHash = hash
+	status.Objects = int32(len(objects))
+	status.LastAppliedTime = &now
+
+	setAddonCondition(kindcluster, status, metav1.ConditionTrue, reasonAddonApplied,
+		fmt.Sprintf("%d objects are applied", len(objects)))
+
+	return true, nil
+}
+
+// Get the name of the object with its namespace, if it has a namespace
+func getObjectName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// Set the Applied condition of the addon
+func setAddonCondition(kindcluster *infrastructurev1alpha1.KINDCluster, status *infrastructurev1alpha1.KINDAddonStatus,
+	conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               infrastructurev1alpha1.AddonAppliedCondition,
+		Status:             conditionStatus,
+		ObservedGeneration: kindcluster.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// Set the AddonsApplied condition of the KINDCluster instance from the first addon that
+// failed, the condition is removed if the spec does not have addons
+func setAddonsAppliedCondition(kindcluster *infrastructurev1alpha1.KINDCluster, failed *infrastructurev1alpha1.KINDAddonStatus) {
+	if len(kindcluster.Spec.Addons) == 0 {
+		meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.AddonsAppliedCondition)
+
+		return
+	}
+
+	conditions := clusterConditions(kindcluster)
+
+	if failed != nil {
+		condition := meta.FindStatusCondition(failed.Conditions, infrastructurev1alpha1.AddonAppliedCondition)
+
+		conditions.set(infrastructurev1alpha1.AddonsAppliedCondition, metav1.ConditionFalse, condition.Reason,
+			fmt.Sprintf("Addon %s is not applied: %s", failed.Name, condition.Message))
+
+		return
+	}
+
+	conditions.set(infrastructurev1alpha1.AddonsAppliedCondition, metav1.ConditionTrue, reasonAddonsApplied,
+		fmt.Sprintf("%d addons are applied", len(kindcluster.Spec.Addons)))
+}
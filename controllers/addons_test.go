@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeWorkloadCluster records the objects that are applied into it and reports the health
+// that is set in it
+type fakeWorkloadCluster struct {
+	applied   []string
+	applyErr  error
+	readyzErr error
+	nodes     []corev1.Node
+}
+
+func (w *fakeWorkloadCluster) Apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	if w.applyErr != nil {
+		return w.applyErr
+	}
+
+	w.applied = append(w.applied, obj.GetKind()+"/"+obj.GetName())
+
+	return nil
+}
+
+func (w *fakeWorkloadCluster) Readyz(ctx context.Context) error {
+	return w.readyzErr
+}
+
+func (w *fakeWorkloadCluster) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	return w.nodes, nil
+}
+
+func Test_ParseAddonManifest(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		manifest string
+		want     int
+		wantErr  bool
+	}{
+		{"multiple documents", "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n---\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: b\n", 2, false},
+		{"list", "apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: Namespace\n  metadata:\n    name: a\n", 1, false},
+		{"json", `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "a"}}`, 1, false},
+		{"empty", "", 0, false},
+		{"invalid", "apiVersion: v1\nkind: [", 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseAddonManifest([]byte(tc.manifest))
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseAddonManifest() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if len(got) != tc.want {
+				t.Errorf("parseAddonManifest() = %d objects, want %d", len(got), tc.want)
+			}
+		})
+	}
+}
+
+func Test_ApplyAddons(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	crds := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "crds", Namespace: defaultNamespace},
+		Data: map[string]string{
+			"crd.yaml": "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: widgets.example.com\n",
+		},
+	}
+
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: defaultNamespace},
+		Data: map[string][]byte{
+			"b.yaml": []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: b\n"),
+			"a.yaml": []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: a\n"),
+		},
+	}
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: defaultNamespace},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName: "test",
+			Addons: []infrastructurev1alpha1.KINDClusterAddon{
+				{Name: "crds", ConfigMapRef: &infrastructurev1alpha1.AddonSourceReference{Name: "crds"}},
+				{Name: "credentials", SecretRef: &infrastructurev1alpha1.AddonSourceReference{Name: "credentials"}},
+			},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, credentials)
+	workload := &fakeWorkloadCluster{}
+
+	r := &KINDClusterReconciler{
+		Client: c,
+		newWorkloadCluster: func(kubeconfig []byte) (workloadCluster, error) {
+			return workload, nil
+		},
+	}
+
+	addonCondition := func(i int) *metav1.Condition {
+		return meta.FindStatusCondition(kindcluster.Status.Addons[i].Conditions, infrastructurev1alpha1.AddonAppliedCondition)
+	}
+
+	// The addons after an addon whose source is missing wait for it
+	failed, err := r.applyAddons(context.Background(), kindcluster, nil, ctrl.Log)
+
+	if err != nil || !failed {
+		t.Fatalf("applyAddons() = %v, %v, want true", failed, err)
+	}
+
+	if addonCondition(0).Reason != reasonAddonSourceNotFound || addonCondition(1).Reason != reasonWaitingForAddon ||
+		len(workload.applied) != 0 {
+		t.Errorf("applyAddons() conditions = %v, %v, applied = %v, want %s and %s", addonCondition(0), addonCondition(1),
+			workload.applied, reasonAddonSourceNotFound, reasonWaitingForAddon)
+	}
+
+	// The addons are applied in their order and the keys in the order of their names
+	if err := c.Create(context.Background(), crds); err != nil {
+		t.Fatal(err)
+	}
+
+	if failed, err := r.applyAddons(context.Background(), kindcluster, nil, ctrl.Log); err != nil || failed {
+		t.Fatalf("applyAddons() = %v, %v, want false", failed, err)
+	}
+
+	want := "[CustomResourceDefinition/widgets.example.com Secret/a Secret/b]"
+
+	if got := fmt.Sprint(workload.applied); got != want {
+		t.Errorf("applyAddons() applied = %v, want %v", got, want)
+	}
+
+	if !meta.IsStatusConditionTrue(kindcluster.Status.Conditions, infrastructurev1alpha1.AddonsAppliedCondition) ||
+		kindcluster.Status.Addons[1].Objects != 2 {
+		t.Errorf("applyAddons() status = %v, conditions = %v, want applied", kindcluster.Status.Addons, kindcluster.Status.Conditions)
+	}
+
+	// The unchanged addons are not applied again, the changed addons are
+	workload.applied = nil
+
+	if _, err := r.applyAddons(context.Background(), kindcluster, nil, ctrl.Log); err != nil || len(workload.applied) != 0 {
+		t.Fatalf("applyAddons() error = %v, applied = %v, want nothing applied", err, workload.applied)
+	}
+
+	crds.Data["crd.yaml"] = "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: gadgets.example.com\n"
+
+	if err := c.Update(context.Background(), crds); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.applyAddons(context.Background(), kindcluster, nil, ctrl.Log); err != nil ||
+		fmt.Sprint(workload.applied) != "[CustomResourceDefinition/gadgets.example.com]" {
+		t.Fatalf("applyAddons() error = %v, applied = %v, want the changed addon applied", err, workload.applied)
+	}
+
+	// A failed application is reported and retried
+	workload.applyErr = errors.New("connection refused")
+
+	credentials.Data["a.yaml"] = []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: c\n")
+
+	if err := c.Update(context.Background(), credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	if failed, err := r.applyAddons(context.Background(), kindcluster, nil, ctrl.Log); err != nil || !failed {
+		t.Fatalf("applyAddons() = %v, %v, want true", failed, err)
+	}
+
+	if condition := meta.FindStatusCondition(kindcluster.Status.Conditions, infrastructurev1alpha1.AddonsAppliedCondition); condition.Reason != reasonAddonApplyFailed {
+		t.Errorf("applyAddons() condition = %v, want %s", condition, reasonAddonApplyFailed)
+	}
+}
+
+func Test_SecretToKINDClusters(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: defaultNamespace},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			RegistryAuth: &infrastructurev1alpha1.RegistryAuthReference{SecretName: "registry-auth"},
+			Addons: []infrastructurev1alpha1.KINDClusterAddon{
+				{Name: "credentials", SecretRef: &infrastructurev1alpha1.AddonSourceReference{Name: "credentials"}},
+			},
+		},
+	}
+
+	r := &KINDClusterReconciler{Client: fake.NewFakeClientWithScheme(testScheme, kindcluster), Log: ctrl.Log}
+
+	var testCases = []struct {
+		name   string
+		object client.Object
+		want   int
+	}{
+		{"addon secret", &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: defaultNamespace}}, 1},
+		{"registry auth secret", &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry-auth", Namespace: defaultNamespace}}, 1},
+		{"other secret", &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: defaultNamespace}}, 0},
+		{"other namespace", &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "other"}}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := r.secretToKINDClusters(tc.object); len(got) != tc.want {
+				t.Errorf("secretToKINDClusters() = %v, want %d requests", got, tc.want)
+			}
+		})
+	}
+}
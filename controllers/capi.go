@@ -0,0 +1,177 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// The Cluster API types are read as unstructured objects, so the provider does not
+// depend on the Cluster API module and it also works without Cluster API
+const (
+	// The annotation that pauses the reconciliation of a Cluster API object
+	pausedAnnotation = "cluster.x-k8s.io/paused"
+
+	// CAPI failure reason of a cluster that cannot be created
+	failureReasonCreateError = "CreateError"
+)
+
+var (
+	// Group version kind of the Cluster API Cluster that owns a KINDCluster
+	capiClusterGVK = schema.GroupVersionKind{
+		Group:   "cluster.x-k8s.io",
+		Version: "v1beta1",
+		Kind:    "Cluster",
+	}
+
+	// Group version kind of the Cluster API Machine that owns a KINDMachine
+	capiMachineGVK = schema.GroupVersionKind{
+		Group:   "cluster.x-k8s.io",
+		Version: "v1beta1",
+		Kind:    "Machine",
+	}
+)
+
+// Get the Cluster API Cluster that owns the object
+// It returns nil if the object is not owned by a Cluster
+func getOwnerCluster(ctx context.Context, c client.Client, obj client.Object) (*unstructured.Unstructured, error) {
+	return getOwner(ctx, c, obj, capiClusterGVK)
+}
+
+// Get the Cluster API Machine that owns the object
+// It returns nil if the object is not owned by a Machine
+func getOwnerMachine(ctx context.Context, c client.Client, obj client.Object) (*unstructured.Unstructured, error) {
+	return getOwner(ctx, c, obj, capiMachineGVK)
+}
+
+// Get the Cluster API Cluster of a Machine from its spec.clusterName field
+// It returns nil if the Cluster cannot be found
+func getMachineCluster(ctx context.Context, c client.Client, machine *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	clusterName, _, _ := unstructured.NestedString(machine.Object, "spec", "clusterName")
+
+	if clusterName == "" {
+		return nil, nil
+	}
+
+	return getObject(ctx, c, capiClusterGVK, types.NamespacedName{Name: clusterName, Namespace: machine.GetNamespace()})
+}
+
+// Get the owner of the object with the specified kind
+// It returns nil if the object does not have such an owner
+func getOwner(ctx context.Context, c client.Client, obj client.Object,
+	gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ref.Kind != gvk.Kind || gv.Group != gvk.Group {
+			continue
+		}
+
+		return getObject(ctx, c, gvk, types.NamespacedName{Name: ref.Name, Namespace: obj.GetNamespace()})
+	}
+
+	return nil, nil
+}
+
+// Get a Cluster API object as an unstructured object
+// It returns nil if the object cannot be found
+func getObject(ctx context.Context, c client.Client, gvk schema.GroupVersionKind,
+	key types.NamespacedName) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+
+	if err := c.Get(ctx, key, obj); err != nil {
+		// The object may already be deleted, or Cluster API may not be installed
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// Check whether the reconciliation of the object is paused, either by the paused
+// annotation on the object or by the spec.paused field of the owner Cluster
+func isPaused(cluster *unstructured.Unstructured, obj client.Object) bool {
+	if _, ok := obj.GetAnnotations()[pausedAnnotation]; ok {
+		return true
+	}
+
+	if cluster == nil {
+		return false
+	}
+
+	paused, _, _ := unstructured.NestedBool(cluster.Object, "spec", "paused")
+
+	return paused
+}
+
+// Map a Cluster API Cluster to the KINDCluster that is its infrastructure reference
+func clusterToKINDCluster(obj client.Object) []reconcile.Request {
+	return infrastructureRefToRequests(obj, infrastructurev1alpha1.KindOfKindCluster)
+}
+
+// Map a Cluster API Machine to the KINDMachine that is its infrastructure reference
+func machineToKINDMachine(obj client.Object) []reconcile.Request {
+	return infrastructureRefToRequests(obj, infrastructurev1alpha1.KindOfKindMachine)
+}
+
+// Map a Cluster API object to its infrastructure reference if it has the specified kind
+func infrastructureRefToRequests(obj client.Object, kind string) []reconcile.Request {
+	cluster, ok := obj.(*unstructured.Unstructured)
+
+	if !ok {
+		return nil
+	}
+
+	ref, ok, _ := unstructured.NestedStringMap(cluster.Object, "spec", "infrastructureRef")
+
+	if !ok || ref["kind"] != kind {
+		return nil
+	}
+
+	if gv, err := schema.ParseGroupVersion(ref["apiVersion"]); err != nil ||
+		gv.Group != infrastructurev1alpha1.GroupVersion.Group {
+		return nil
+	}
+
+	namespace := ref["namespace"]
+
+	if namespace == "" {
+		namespace = cluster.GetNamespace()
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name:      ref["name"],
+		Namespace: namespace,
+	}}}
+}
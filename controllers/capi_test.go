@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Get a Cluster API Cluster that references the KINDCluster as its infrastructure
+func newCAPICluster(name string, paused bool) *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"paused": paused,
+			"infrastructureRef": map[string]interface{}{
+				"apiVersion": infrastructurev1alpha1.GroupVersion.String(),
+				"kind":       infrastructurev1alpha1.KindOfKindCluster,
+				"name":       name,
+			},
+		},
+	}}
+	cluster.SetGroupVersionKind(capiClusterGVK)
+	cluster.SetName(name)
+	cluster.SetNamespace(defaultNamespace)
+
+	return cluster
+}
+
+func Test_IsPaused(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		cluster     *unstructured.Unstructured
+		annotations map[string]string
+		paused      bool
+	}{
+		{"standalone", nil, nil, false},
+		{"annotation", nil, map[string]string{pausedAnnotation: ""}, true},
+		{"owner-paused", newCAPICluster("test", true), nil, true},
+		{"owner-unpaused", newCAPICluster("test", false), nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := &infrastructurev1alpha1.KINDCluster{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+			}
+
+			if got := isPaused(tc.cluster, kindcluster); got != tc.paused {
+				t.Errorf("isPaused() = %v, want %v", got, tc.paused)
+			}
+		})
+	}
+}
+
+func Test_ClusterToKINDCluster(t *testing.T) {
+	requests := clusterToKINDCluster(newCAPICluster("test", false))
+
+	if len(requests) != 1 || requests[0].Name != "test" || requests[0].Namespace != defaultNamespace {
+		t.Errorf("clusterToKINDCluster() = %v, want a request for %s/test", requests, defaultNamespace)
+	}
+
+	other := newCAPICluster("test", false)
+	other.Object["spec"].(map[string]interface{})["infrastructureRef"].(map[string]interface{})["kind"] = "DockerCluster"
+
+	if requests := clusterToKINDCluster(other); len(requests) != 0 {
+		t.Errorf("clusterToKINDCluster() = %v, want no requests for another infrastructure", requests)
+	}
+}
+
+func Test_GetOwnerCluster(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	cluster := newCAPICluster("test", true)
+	c := fake.NewFakeClientWithScheme(testScheme, cluster)
+
+	owned := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: defaultNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capiClusterGVK.GroupVersion().String(),
+				Kind:       capiClusterGVK.Kind,
+				Name:       "test",
+			}},
+		},
+	}
+
+	owner, err := getOwnerCluster(context.Background(), c, owned)
+
+	if err != nil || owner == nil || owner.GetName() != "test" {
+		t.Errorf("getOwnerCluster() = %v, %v, want the Cluster test", owner, err)
+	}
+
+	// A missing owner is ignored, for example when the Cluster was already deleted
+	owned.OwnerReferences[0].Name = "deleted"
+
+	if owner, err := getOwnerCluster(context.Background(), c, owned); err != nil || owner != nil {
+		t.Errorf("getOwnerCluster() = %v, %v, want no owner", owner, err)
+	}
+}
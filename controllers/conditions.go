@@ -0,0 +1,218 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The maximum number of events that are kept in the history of a status
+const maxHistoryEvents = 10
+
+// Reasons of the status conditions
+const (
+	reasonProvisioning       = "Provisioning"
+	reasonProvisioned        = "Provisioned"
+	reasonRecreating         = "Recreating"
+	reasonDrifted            = "Drifted"
+	reasonNodesMatchSpec     = "NodesMatchSpec"
+	reasonSecretsStored      = "SecretsStored"
+	reasonSecretError        = "SecretError"
+	reasonReady              = "Ready"
+	reasonWaitingForCluster  = "WaitingForCluster"
+	reasonSupportedVersion   = "SupportedVersion"
+	reasonDeprecatedVersion  = "DeprecatedVersion"
+	reasonUnsupportedVersion = "UnsupportedVersion"
+	reasonCustomImage        = "CustomImage"
+	reasonKindConfigMerged   = "KindConfigMerged"
+	reasonInvalidKindConfig  = "InvalidKindConfig"
+	reasonKindConfigConflict = "KindConfigConflict"
+	reasonKindConfigNotFound = "KindConfigNotFound"
+	reasonHostPortConflict   = "HostPortConflict"
+	reasonRegistryAvailable  = "RegistryAvailable"
+	reasonRegistryError      = "RegistryError"
+	reasonRegistryAuthError  = "RegistryAuthError"
+	reasonCredentialsWritten = "CredentialsWritten"
+	reasonImagesLoaded       = "ImagesLoaded"
+	reasonImagesLoading      = "ImagesLoading"
+	reasonImageLoadFailed    = "ImageLoadFailed"
+
+	reasonAddonsApplied        = "AddonsApplied"
+	reasonAddonApplied         = "Applied"
+	reasonAddonApplyFailed     = "ApplyFailed"
+	reasonAddonSourceNotFound  = "SourceNotFound"
+	reasonInvalidAddonManifest = "InvalidManifest"
+	reasonWaitingForAddon      = "WaitingForAddon"
+
+	reasonNodesReady        = "NodesReady"
+	reasonNodesNotReady     = "NodesNotReady"
+	reasonAPIServerNotReady = "APIServerNotReady"
+
+	reasonDriftDetectionDisabled = "DriftDetectionDisabled"
+)
+
+// conditionSetter sets the status conditions of an object and records their changes
+// in the history of the object
+type conditionSetter struct {
+	conditions *[]metav1.Condition
+	history    *[]infrastructurev1alpha1.KINDStatusEvent
+	generation int64
+}
+
+// Set the status condition, it replaces the existing condition of the same type
+// A change of the status or the reason of the condition is recorded in the history,
+// the oldest events are dropped so that the history does not grow unbounded.
+func (s conditionSetter) set(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	previous := meta.FindStatusCondition(*s.conditions, conditionType)
+	changed := previous == nil || previous.Status != status || previous.Reason != reason
+
+	meta.SetStatusCondition(s.conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: s.generation,
+		Reason:             reason,
+		Message:            message,
+	})
+
+	if !changed {
+		return
+	}
+
+	*s.history = append(*s.history, infrastructurev1alpha1.KINDStatusEvent{
+		Timestamp: metav1.Now(),
+		Type:      conditionType,
+		Status:    status,
+		Reason:    reason,
+		Message:   message,
+	})
+
+	if len(*s.history) > maxHistoryEvents {
+		*s.history = (*s.history)[len(*s.history)-maxHistoryEvents:]
+	}
+}
+
+// Get the condition setter of the KINDCluster instance
+func clusterConditions(kindcluster *infrastructurev1alpha1.KINDCluster) conditionSetter {
+	return conditionSetter{
+		conditions: &kindcluster.Status.Conditions,
+		history:    &kindcluster.Status.History,
+		generation: kindcluster.Generation,
+	}
+}
+
+// Get the condition setter of the KINDMachine instance
+func machineConditions(kindmachine *infrastructurev1alpha1.KINDMachine) conditionSetter {
+	return conditionSetter{
+		conditions: &kindmachine.Status.Conditions,
+		history:    &kindmachine.Status.History,
+		generation: kindmachine.Generation,
+	}
+}
+
+// Set the Ready condition of the KINDCluster instance from its other conditions, the
+// cluster is ready when it is provisioned, its kubeconfig is available and its nodes are ready
+// The NodesReady condition is only checked if it is reported, it is not reported if the
+// health of the clusters is not probed.
+func setClusterReadyCondition(kindcluster *infrastructurev1alpha1.KINDCluster) {
+	conditions := clusterConditions(kindcluster)
+
+	for _, conditionType := range []string{
+		infrastructurev1alpha1.ClusterProvisionedCondition,
+		infrastructurev1alpha1.KubeconfigAvailableCondition,
+	} {
+		condition := meta.FindStatusCondition(kindcluster.Status.Conditions, conditionType)
+
+		if condition == nil {
+			conditions.set(infrastructurev1alpha1.ReadyCondition, metav1.ConditionFalse, reasonProvisioning,
+				conditionType+" condition is not reported yet")
+
+			return
+		}
+
+		if condition.Status != metav1.ConditionTrue {
+			conditions.set(infrastructurev1alpha1.ReadyCondition, metav1.ConditionFalse, condition.Reason, condition.Message)
+
+			return
+		}
+	}
+
+	if condition := meta.FindStatusCondition(kindcluster.Status.Conditions, infrastructurev1alpha1.NodesReadyCondition); condition != nil &&
+		condition.Status != metav1.ConditionTrue {
+		conditions.set(infrastructurev1alpha1.ReadyCondition, metav1.ConditionFalse, condition.Reason, condition.Message)
+
+		return
+	}
+
+	conditions.set(infrastructurev1alpha1.ReadyCondition, metav1.ConditionTrue, reasonReady, "Cluster is ready")
+}
+
+// Set the KubernetesVersionSupported condition of the KINDCluster instance from the image catalog
+// It returns false if the cluster cannot be created, that is the version is not in the catalog
+// and no node image is set explicitly
+func setKubernetesVersionCondition(kindcluster *infrastructurev1alpha1.KINDCluster,
+	catalog infrastructurev1alpha1.ImageCatalog) bool {
+	conditions := clusterConditions(kindcluster)
+	version := kindcluster.Spec.KubernetesVersion
+
+	image, ok := catalog.Resolve(version)
+
+	switch {
+	case !ok && kindcluster.Spec.NodeImage == "":
+		conditions.set(infrastructurev1alpha1.KubernetesVersionSupportedCondition, metav1.ConditionFalse,
+			reasonUnsupportedVersion, fmt.Sprintf("Kubernetes version %s is not in the image catalog", version))
+
+		return false
+	case !ok:
+		conditions.set(infrastructurev1alpha1.KubernetesVersionSupportedCondition, metav1.ConditionTrue,
+			reasonCustomImage, fmt.Sprintf("Node image %s is used", kindcluster.Spec.NodeImage))
+	case image.Deprecated:
+		conditions.set(infrastructurev1alpha1.KubernetesVersionSupportedCondition, metav1.ConditionTrue,
+			reasonDeprecatedVersion, fmt.Sprintf("Kubernetes version %s is deprecated", version))
+	default:
+		conditions.set(infrastructurev1alpha1.KubernetesVersionSupportedCondition, metav1.ConditionTrue,
+			reasonSupportedVersion, fmt.Sprintf("Kubernetes version %s is supported", version))
+	}
+
+	return true
+}
+
+// Set the KindConfigValid condition of the KINDCluster instance from the result of the merge
+// of its kind cluster configuration, the condition is reported only if the spec has a raw
+// kind cluster configuration
+func setKindConfigCondition(kindcluster *infrastructurev1alpha1.KINDCluster, configError *kindConfigError) {
+	if kindcluster.Spec.KindConfig == "" && kindcluster.Spec.KindConfigRef == nil {
+		meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.KindConfigValidCondition)
+
+		return
+	}
+
+	conditions := clusterConditions(kindcluster)
+
+	if configError != nil {
+		conditions.set(infrastructurev1alpha1.KindConfigValidCondition, metav1.ConditionFalse,
+			configError.reason, configError.message)
+
+		return
+	}
+
+	conditions.set(infrastructurev1alpha1.KindConfigValidCondition, metav1.ConditionTrue,
+		reasonKindConfigMerged, "Kind configuration is merged with the spec")
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_ConditionSetter(t *testing.T) {
+	kindcluster := &infrastructurev1alpha1.KINDCluster{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+	conditions := clusterConditions(kindcluster)
+
+	conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse, reasonProvisioning, "step 1")
+
+	// A new message with the same status and reason does not add an event to the history
+	conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse, reasonProvisioning, "step 2")
+
+	if len(kindcluster.Status.Conditions) != 1 || len(kindcluster.Status.History) != 1 {
+		t.Fatalf("set() conditions = %v, history = %v, want one of each",
+			kindcluster.Status.Conditions, kindcluster.Status.History)
+	}
+
+	condition := kindcluster.Status.Conditions[0]
+
+	if condition.Message != "step 2" || condition.ObservedGeneration != 2 {
+		t.Errorf("set() condition = %+v, want the last message and the generation", condition)
+	}
+
+	// The history keeps only the most recent events
+	for i := 0; i < 2*maxHistoryEvents; i++ {
+		conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+			fmt.Sprintf("Reason%d", i), "changed")
+	}
+
+	if len(kindcluster.Status.History) != maxHistoryEvents {
+		t.Errorf("set() history length = %d, want %d", len(kindcluster.Status.History), maxHistoryEvents)
+	}
+
+	if last := kindcluster.Status.History[maxHistoryEvents-1]; last.Reason != fmt.Sprintf("Reason%d", 2*maxHistoryEvents-1) {
+		t.Errorf("set() last event = %+v, want the most recent change", last)
+	}
+}
+
+func Test_SetClusterReadyCondition(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		provisioned metav1.ConditionStatus
+		kubeconfig  metav1.ConditionStatus
+		nodesReady  metav1.ConditionStatus
+		ready       metav1.ConditionStatus
+		reason      string
+	}{
+		{"ready", metav1.ConditionTrue, metav1.ConditionTrue, "", metav1.ConditionTrue, reasonReady},
+		{"provisioning", metav1.ConditionFalse, "", "", metav1.ConditionFalse, reasonProvisioning},
+		{"kubeconfig-missing", metav1.ConditionTrue, "", "", metav1.ConditionFalse, reasonProvisioning},
+		{"secret-error", metav1.ConditionTrue, metav1.ConditionFalse, "", metav1.ConditionFalse, reasonSecretError},
+		{"nodes-ready", metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionTrue, reasonReady},
+		{"nodes-not-ready", metav1.ConditionTrue, metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, reasonNodesNotReady},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := &infrastructurev1alpha1.KINDCluster{}
+			conditions := clusterConditions(kindcluster)
+
+			conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, tc.provisioned, reasonProvisioning, "")
+
+			if tc.kubeconfig != "" {
+				conditions.set(infrastructurev1alpha1.KubeconfigAvailableCondition, tc.kubeconfig, reasonSecretError, "")
+			}
+
+			if tc.nodesReady != "" {
+				conditions.set(infrastructurev1alpha1.NodesReadyCondition, tc.nodesReady, reasonNodesNotReady, "")
+			}
+
+			setClusterReadyCondition(kindcluster)
+
+			ready := meta.FindStatusCondition(kindcluster.Status.Conditions, infrastructurev1alpha1.ReadyCondition)
+
+			if ready == nil || ready.Status != tc.ready || ready.Reason != tc.reason {
+				t.Errorf("setClusterReadyCondition() = %+v, want status %s with reason %s", ready, tc.ready, tc.reason)
+			}
+		})
+	}
+}
+
+func Test_SetKubernetesVersionCondition(t *testing.T) {
+	catalog := infrastructurev1alpha1.NewImageCatalog([]infrastructurev1alpha1.KINDImageCatalog{{
+		Spec: infrastructurev1alpha1.KINDImageCatalogSpec{Images: []infrastructurev1alpha1.KINDImage{
+			{Version: "1.14", Image: "kindest/node:v1.14.10", Deprecated: true},
+		}},
+	}})
+
+	var testCases = []struct {
+		name      string
+		version   string
+		nodeImage string
+		supported bool
+		reason    string
+	}{
+		{"supported", "1.21", "", true, reasonSupportedVersion},
+		{"deprecated", "1.14", "", true, reasonDeprecatedVersion},
+		{"unsupported", "1.99", "", false, reasonUnsupportedVersion},
+		{"unknown patch version", "v1.21.999", "", false, reasonUnsupportedVersion},
+		{"custom-image", "1.99", "registry.local/node:v1.99.0", true, reasonCustomImage},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := &infrastructurev1alpha1.KINDCluster{Spec: infrastructurev1alpha1.KINDClusterSpec{
+				KubernetesVersion: tc.version,
+				NodeImage:         tc.nodeImage,
+			}}
+
+			if got := setKubernetesVersionCondition(kindcluster, catalog); got != tc.supported {
+				t.Errorf("setKubernetesVersionCondition() = %v, want %v", got, tc.supported)
+			}
+
+			condition := meta.FindStatusCondition(kindcluster.Status.Conditions,
+				infrastructurev1alpha1.KubernetesVersionSupportedCondition)
+
+			if condition == nil || condition.Reason != tc.reason {
+				t.Errorf("setKubernetesVersionCondition() condition = %+v, want reason %s", condition, tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+// The tags of the node images that are Kubernetes versions
+var versionTagRE = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+`)
+
+// Get the Kubernetes version that the nodes with the image should run
+// It is the tag of the image if it is a version, the image of a role can be of another
+// version than the spec. The patch version in the spec is the version of a custom-built
+// image of the cluster, the version of a custom-built image of a role is not known, so it
+// is not compared.
+func getDesiredNodeVersion(kindcluster *infrastructurev1alpha1.KINDCluster, image string) string {
+	if tag := backend.ImageVersion(image); versionTagRE.MatchString(tag) {
+		return tag
+	}
+
+	version := "v" + strings.TrimPrefix(kindcluster.Spec.KubernetesVersion, "v")
+
+	if strings.Count(version, ".") == 2 && image == kindcluster.Spec.NodeImage {
+		return version
+	}
+
+	return ""
+}
+
+// Detect the differences between the kind cluster configuration of KINDCluster instance and
+// the running nodes
+// The numbers of nodes per role and the kubernetes versions of the nodes are compared,
+// the nodes that belong to KINDMachines are ignored
+func detectDrift(kindcluster *infrastructurev1alpha1.KINDCluster, config *v1alpha4.Cluster, nodes []backend.Node) []string {
+	var drift []string
+
+	desiredCounts := map[v1alpha4.NodeRole]int{}
+	desiredVersions := map[v1alpha4.NodeRole]string{}
+
+	for _, node := range config.Nodes {
+		desiredCounts[node.Role]++
+
+		// kind uses its default node image if no image is configured
+		image := node.Image
+
+		if image == "" {
+			image = defaults.Image
+		}
+
+		desiredVersions[node.Role] = getDesiredNodeVersion(kindcluster, image)
+	}
+
+	actualCounts := map[v1alpha4.NodeRole]int{}
+
+	for _, node := range nodes {
+		role := v1alpha4.NodeRole(node.Role)
+
+		// The nodes of KINDMachines are not part of the topology of the cluster
+		if (role != v1alpha4.ControlPlaneRole && role != v1alpha4.WorkerRole) || node.Machine != "" {
+			continue
+		}
+
+		actualCounts[role]++
+
+		if desired := desiredVersions[role]; desired != "" && node.KubernetesVersion != desired {
+			drift = append(drift, fmt.Sprintf("node %s runs kubernetes %s, spec wants %s",
+				node.Name, node.KubernetesVersion, desired))
+		}
+	}
+
+	for _, role := range []v1alpha4.NodeRole{v1alpha4.ControlPlaneRole, v1alpha4.WorkerRole} {
+		if desiredCounts[role] != actualCounts[role] {
+			drift = append(drift, fmt.Sprintf("cluster has %d %s nodes, spec wants %d",
+				actualCounts[role], role, desiredCounts[role]))
+		}
+	}
+
+	return drift
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+func Test_DetectDrift(t *testing.T) {
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test",
+			KubernetesVersion: "1.21",
+			Topology: &infrastructurev1alpha1.KINDClusterTopology{
+				ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1},
+				Workers:      infrastructurev1alpha1.WorkerTopology{Replicas: 1},
+			},
+		},
+	}
+
+	var testCases = []struct {
+		name  string
+		nodes []backend.Node
+		drift int
+	}{
+		{"matching", []backend.Node{
+			{Name: "test-control-plane", Role: "control-plane", KubernetesVersion: "v1.21.1"},
+			{Name: "test-worker", Role: "worker", KubernetesVersion: "v1.21.1"},
+		}, 0},
+		{"version", []backend.Node{
+			{Name: "test-control-plane", Role: "control-plane", KubernetesVersion: "v1.20.7"},
+			{Name: "test-worker", Role: "worker", KubernetesVersion: "v1.20.7"},
+		}, 2},
+		{"topology", []backend.Node{
+			{Name: "test-control-plane", Role: "control-plane", KubernetesVersion: "v1.21.1"},
+		}, 1},
+		{"load-balancer", []backend.Node{
+			{Name: "test-control-plane", Role: "control-plane", KubernetesVersion: "v1.21.1"},
+			{Name: "test-worker", Role: "worker", KubernetesVersion: "v1.21.1"},
+			{Name: "test-external-load-balancer", Role: "external-load-balancer"},
+		}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectDrift(kindcluster, buildKindConfig(kindcluster), tc.nodes); len(got) != tc.drift {
+				t.Errorf("detectDrift() = %v, want %d differences", got, tc.drift)
+			}
+		})
+	}
+}
+
+func Test_DetectDriftRoleImage(t *testing.T) {
+	// The workers run another version than the patch version of the cluster
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "t",
+			KubernetesVersion: "v1.21.14",
+			NodeImage:         "kindest/node:v1.21.14",
+			Topology: &infrastructurev1alpha1.KINDClusterTopology{
+				ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1},
+				Workers: infrastructurev1alpha1.WorkerTopology{
+					Replicas:         1,
+					KINDNodeTemplate: infrastructurev1alpha1.KINDNodeTemplate{NodeImage: "kindest/node:v1.22.0"},
+				},
+			},
+		},
+	}
+
+	nodes := []backend.Node{
+		{Name: "t-control-plane", Role: "control-plane", KubernetesVersion: "v1.21.14"},
+		{Name: "t-worker", Role: "worker", KubernetesVersion: "v1.22.0"},
+	}
+
+	if got := detectDrift(kindcluster, buildKindConfig(kindcluster), nodes); len(got) != 0 {
+		t.Errorf("detectDrift() = %v, want no differences", got)
+	}
+
+	// A worker of the version of the cluster does not match the image of the workers
+	nodes[1].KubernetesVersion = "v1.21.14"
+
+	if got := detectDrift(kindcluster, buildKindConfig(kindcluster), nodes); len(got) != 1 {
+		t.Errorf("detectDrift() = %v, want 1 difference", got)
+	}
+}
+
+func Test_GetDesiredNodeVersion(t *testing.T) {
+	var testCases = []struct {
+		version   string
+		nodeImage string
+		image     string
+		want      string
+	}{
+		{"1.21", "", "kindest/node:v1.21.1@sha256:69860bda5563ac81e3c0057d654b5253219618a22ec3a346306239bba8cfa1a6", "v1.21.1"},
+		{"v1.21.14", "registry.local/node:custom", "registry.local/node:custom", "v1.21.14"},
+		{"1.21.14", "", "kindest/node:v1.21.14", "v1.21.14"},
+		{"1.21", "registry.local/node:custom", "registry.local/node:custom", ""},
+		{"v1.21.14", "", "kindest/node:v1.22.0", "v1.22.0"},
+		{"v1.21.14", "", "registry.local/node:worker", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version+"/"+tc.image, func(t *testing.T) {
+			kindcluster := &infrastructurev1alpha1.KINDCluster{
+				Spec: infrastructurev1alpha1.KINDClusterSpec{KubernetesVersion: tc.version, NodeImage: tc.nodeImage},
+			}
+
+			if got := getDesiredNodeVersion(kindcluster, tc.image); got != tc.want {
+				t.Errorf("getDesiredNodeVersion() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_ReconcileDriftRecreate(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-drift",
+			Namespace:  defaultNamespace,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-drift",
+			KubernetesVersion: "1.21",
+			DriftPolicy:       infrastructurev1alpha1.DriftPolicyRecreate,
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+	b := backend.NewFakeBackend()
+
+	// The running cluster was created with an older kubernetes version
+	if err := b.Create(kindcluster.Spec.ClusterName, &v1alpha4.Cluster{
+		Nodes: []v1alpha4.Node{{Role: v1alpha4.ControlPlaneRole, Image: "kindest/node:v1.20.7"}},
+	}, backend.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &KINDClusterReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend: b,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	result, err := r.Reconcile(context.Background(), req)
+
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if !result.Requeue {
+		t.Errorf("Reconcile() requeue = false, want true")
+	}
+
+	if clusters, _ := b.List(); len(clusters) != 0 {
+		t.Errorf("Reconcile() clusters = %v, want the drifted cluster deleted", clusters)
+	}
+}
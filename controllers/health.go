@@ -0,0 +1,107 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Probe the health of the cluster through its API server and report it in the NodesReady
+// condition, the cluster is healthy when the API server is ready and all nodes are
+// registered and ready
+// The probe errors are reported in the condition, they are not returned, so the cluster is
+// probed again in the next reconciliation.
+func (r *KINDClusterReconciler) probeClusterHealth(ctx context.Context, kindcluster *infrastructurev1alpha1.KINDCluster,
+	kubeconfig []byte, log logr.Logger) {
+	clusterName := kindcluster.Spec.ClusterName
+	conditions := clusterConditions(kindcluster)
+
+	workload, err := r.getWorkloadCluster(clusterName, kubeconfig)
+
+	if err == nil {
+		err = workload.Readyz(ctx)
+	}
+
+	if err != nil {
+		log.Info("API server of cluster is not ready", clusterNameKey, clusterName, "error", err.Error())
+
+		conditions.set(infrastructurev1alpha1.NodesReadyCondition, metav1.ConditionFalse, reasonAPIServerNotReady,
+			fmt.Sprintf("API server is not ready: %s", err))
+
+		return
+	}
+
+	nodes, err := workload.ListNodes(ctx)
+
+	if err != nil {
+		log.Info("Nodes of cluster cannot be listed", clusterNameKey, clusterName, "error", err.Error())
+
+		conditions.set(infrastructurev1alpha1.NodesReadyCondition, metav1.ConditionFalse, reasonAPIServerNotReady,
+			fmt.Sprintf("Nodes cannot be listed: %s", err))
+
+		return
+	}
+
+	if notReady := getNotReadyNodes(nodes); len(notReady) > 0 {
+		conditions.set(infrastructurev1alpha1.NodesReadyCondition, metav1.ConditionFalse, reasonNodesNotReady,
+			fmt.Sprintf("Nodes are not ready: %s", strings.Join(notReady, ", ")))
+
+		return
+	}
+
+	// The nodes that are running but did not join the cluster are not listed by the API server
+	if expected := int(kindcluster.Status.NodeCount); len(nodes) < expected {
+		conditions.set(infrastructurev1alpha1.NodesReadyCondition, metav1.ConditionFalse, reasonNodesNotReady,
+			fmt.Sprintf("%d of %d nodes are registered", len(nodes), expected))
+
+		return
+	}
+
+	conditions.set(infrastructurev1alpha1.NodesReadyCondition, metav1.ConditionTrue, reasonNodesReady,
+		fmt.Sprintf("%d nodes are ready", len(nodes)))
+}
+
+// Get the sorted names of the nodes whose Ready condition is not true
+func getNotReadyNodes(nodes []corev1.Node) []string {
+	var notReady []string
+
+	for _, node := range nodes {
+		ready := false
+
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady {
+				ready = condition.Status == corev1.ConditionTrue
+			}
+		}
+
+		if !ready {
+			notReady = append(notReady, node.Name)
+		}
+	}
+
+	sort.Strings(notReady)
+
+	return notReady
+}
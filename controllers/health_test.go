@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Get a node of the workload cluster with the status of its Ready condition
+func newWorkloadNode(name string, ready corev1.ConditionStatus) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: ready},
+		}},
+	}
+}
+
+func Test_ProbeClusterHealth(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		workload   *fakeWorkloadCluster
+		connectErr error
+		status     metav1.ConditionStatus
+		reason     string
+	}{
+		{"healthy", &fakeWorkloadCluster{nodes: []corev1.Node{
+			newWorkloadNode("test-control-plane", corev1.ConditionTrue),
+			newWorkloadNode("test-worker", corev1.ConditionTrue),
+		}}, nil, metav1.ConditionTrue, reasonNodesReady},
+		{"node not ready", &fakeWorkloadCluster{nodes: []corev1.Node{
+			newWorkloadNode("test-control-plane", corev1.ConditionTrue),
+			newWorkloadNode("test-worker", corev1.ConditionFalse),
+		}}, nil, metav1.ConditionFalse, reasonNodesNotReady},
+		{"node not registered", &fakeWorkloadCluster{nodes: []corev1.Node{
+			newWorkloadNode("test-control-plane", corev1.ConditionTrue),
+		}}, nil, metav1.ConditionFalse, reasonNodesNotReady},
+		{"API server not ready", &fakeWorkloadCluster{readyzErr: errors.New("[-]etcd failed")},
+			nil, metav1.ConditionFalse, reasonAPIServerNotReady},
+		{"connection error", nil, errors.New("invalid kubeconfig"), metav1.ConditionFalse, reasonAPIServerNotReady},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &KINDClusterReconciler{
+				newWorkloadCluster: func(kubeconfig []byte) (workloadCluster, error) {
+					return tc.workload, tc.connectErr
+				},
+			}
+
+			kindcluster := &infrastructurev1alpha1.KINDCluster{
+				Spec:   infrastructurev1alpha1.KINDClusterSpec{ClusterName: "test"},
+				Status: infrastructurev1alpha1.KINDClusterStatus{NodeCount: 2},
+			}
+
+			r.probeClusterHealth(context.Background(), kindcluster, nil, ctrl.Log)
+
+			condition := meta.FindStatusCondition(kindcluster.Status.Conditions, infrastructurev1alpha1.NodesReadyCondition)
+
+			if condition == nil || condition.Status != tc.status || condition.Reason != tc.reason {
+				t.Errorf("probeClusterHealth() = %+v, want status %s with reason %s", condition, tc.status, tc.reason)
+			}
+		})
+	}
+}
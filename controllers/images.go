@@ -0,0 +1,178 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Keys for logs
+const imageNameKey = "image"
+
+// Type of the background operation that loads a preload image into the nodes
+const operationTypeLoadImage = "LoadImage"
+
+// Get the key of the background operation that loads the image into the nodes of the cluster,
+// the cluster names cannot contain a slash, so it does not collide with the cluster operations
+func getImageLoadOperationKey(clusterName, image string) string {
+	return clusterName + "/images/" + image
+}
+
+// Get the hash of the names of the nodes, it changes when a node is added to the cluster,
+// for example by a KINDMachine, or a node is removed
+func hashNodeNames(nodes []backend.Node) string {
+	hash := sha256.New()
+
+	for _, name := range getNodeNames(nodes) {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Load the preload images of the KINDCluster instance that are not loaded into the current
+// nodes of the cluster yet and report their load status
+// The images are loaded in background operations, so that large images do not block the
+// reconcile worker, and the results of the finished operations are recorded. The statuses of
+// the images that were removed from the spec are dropped. It returns whether an image is being
+// loaded and whether an image failed to load, the failed images are retried in the next
+// reconciliation.
+func preloadImages(clusterBackend backend.ClusterBackend, operations *operationTracker,
+	kindcluster *infrastructurev1alpha1.KINDCluster, nodes []backend.Node, log logr.Logger) (bool, bool) {
+	clusterName := kindcluster.Spec.ClusterName
+	nodesHash := hashNodeNames(nodes)
+
+	previous := map[string]infrastructurev1alpha1.KINDImageLoadStatus{}
+
+	for _, status := range kindcluster.Status.PreloadedImages {
+		previous[status.Image] = status
+
+		// The results of the removed images are not recorded
+		if !containsString(status.Image, kindcluster.Spec.PreloadImages) {
+			operations.remove(getImageLoadOperationKey(clusterName, status.Image))
+		}
+	}
+
+	var statuses []infrastructurev1alpha1.KINDImageLoadStatus
+
+	var loading, failed []string
+
+	for _, image := range kindcluster.Spec.PreloadImages {
+		status, ok := previous[image]
+		status.Image = image
+
+		key := getImageLoadOperationKey(clusterName, image)
+
+		// Record the progress or the result of the tracked load operation of the image
+		if op, tracked := operations.get(key); tracked {
+			switch {
+			case !op.Done:
+				status.State = infrastructurev1alpha1.ImageLoadStateLoading
+
+				loading = append(loading, image)
+			case op.Err != nil:
+				log.Error(op.Err, "unable to load image into cluster", clusterNameKey, clusterName, imageNameKey, image)
+
+				status.State = infrastructurev1alpha1.ImageLoadStateFailed
+				status.Message = op.Err.Error()
+
+				failed = append(failed, image)
+			default:
+				log.Info("Image is loaded into cluster", clusterNameKey, clusterName, imageNameKey, image)
+
+				status.State = infrastructurev1alpha1.ImageLoadStateLoaded
+				status.Message = ""
+			}
+
+			if op.Done {
+				operations.remove(key)
+			}
+
+			statuses = append(statuses, status)
+
+			continue
+		}
+
+		// The image is loaded again if a node was added after it was loaded
+		if ok && status.State == infrastructurev1alpha1.ImageLoadStateLoaded && status.NodesHash == nodesHash {
+			statuses = append(statuses, status)
+
+			continue
+		}
+
+		log.Info("Image is being loaded into cluster", clusterNameKey, clusterName, imageNameKey, image)
+
+		image := image
+
+		operations.start(key, operationTypeLoadImage, func(setStep func(string)) error {
+			return clusterBackend.LoadImage(clusterName, image)
+		})
+
+		status.State = infrastructurev1alpha1.ImageLoadStateLoading
+		status.Attempts++
+		status.LastAttemptTime = metav1.Now()
+		status.NodesHash = nodesHash
+
+		loading = append(loading, image)
+		statuses = append(statuses, status)
+	}
+
+	kindcluster.Status.PreloadedImages = statuses
+
+	setImagesPreloadedCondition(kindcluster, loading, failed)
+
+	return len(loading) > 0, len(failed) > 0
+}
+
+// Set the ImagesPreloaded condition of the KINDCluster instance from the images that are being
+// loaded and that failed to load, the condition is removed if the spec does not have preload images
+func setImagesPreloadedCondition(kindcluster *infrastructurev1alpha1.KINDCluster, loading, failed []string) {
+	if len(kindcluster.Spec.PreloadImages) == 0 {
+		meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition)
+
+		return
+	}
+
+	conditions := clusterConditions(kindcluster)
+
+	if len(failed) > 0 {
+		conditions.set(infrastructurev1alpha1.ImagesPreloadedCondition, metav1.ConditionFalse, reasonImageLoadFailed,
+			fmt.Sprintf("Images failed to load, they will be retried: %s", strings.Join(failed, ", ")))
+
+		return
+	}
+
+	if len(loading) > 0 {
+		conditions.set(infrastructurev1alpha1.ImagesPreloadedCondition, metav1.ConditionFalse, reasonImagesLoading,
+			fmt.Sprintf("Images are being loaded: %s", strings.Join(loading, ", ")))
+
+		return
+	}
+
+	conditions.set(infrastructurev1alpha1.ImagesPreloadedCondition, metav1.ConditionTrue, reasonImagesLoaded,
+		fmt.Sprintf("%d images are loaded into all nodes", len(kindcluster.Spec.PreloadImages)))
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func Test_PreloadImages(t *testing.T) {
+	b := backend.NewFakeBackend()
+
+	if err := b.Create("test", nil, backend.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	b.LoadImageErrors = map[string]error{"/images/app.tar": errors.New("no such file")}
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:   "test",
+			PreloadImages: []string{"nginx:1.21", "/images/app.tar"},
+		},
+	}
+
+	operations := &operationTracker{}
+
+	// Load the images in the background and record the results of the finished loads
+	preload := func() (bool, bool) {
+		nodes, err := b.ListNodes("test")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loading, failed := preloadImages(b, operations, kindcluster, nodes, ctrl.Log)
+
+		for _, image := range kindcluster.Spec.PreloadImages {
+			waitForOperation(t, operations, getImageLoadOperationKey("test", image))
+		}
+
+		return loading, failed
+	}
+
+	// The images are being loaded until the results of the loads are recorded
+	if loading, failed := preload(); !loading || failed {
+		t.Fatalf("preloadImages() = %v, %v, want true, false", loading, failed)
+	}
+
+	if got := kindcluster.Status.PreloadedImages[0]; got.State != infrastructurev1alpha1.ImageLoadStateLoading {
+		t.Errorf("preloadImages() status = %v, want Loading", got)
+	}
+
+	if condition := meta.FindStatusCondition(kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition); condition == nil ||
+		condition.Reason != reasonImagesLoading {
+		t.Errorf("preloadImages() conditions = %v, want ImagesPreloaded False with reason %s",
+			kindcluster.Status.Conditions, reasonImagesLoading)
+	}
+
+	// The failed image is reported and the loaded image is not loaded again on retry
+	if loading, failed := preload(); loading || !failed {
+		t.Fatalf("preloadImages() = %v, %v, want false, true", loading, failed)
+	}
+
+	if got := kindcluster.Status.PreloadedImages[1]; got.State != infrastructurev1alpha1.ImageLoadStateFailed ||
+		got.Message != "no such file" || got.Attempts != 1 {
+		t.Errorf("preloadImages() status = %v, want Failed after 1 attempt", got)
+	}
+
+	if !meta.IsStatusConditionFalse(kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition) {
+		t.Errorf("preloadImages() conditions = %v, want ImagesPreloaded False", kindcluster.Status.Conditions)
+	}
+
+	b.LoadImageErrors = nil
+
+	preload()
+
+	if loading, failed := preload(); loading || failed {
+		t.Fatalf("preloadImages() = %v, %v, want false, false", loading, failed)
+	}
+
+	if got := kindcluster.Status.PreloadedImages[1]; got.State != infrastructurev1alpha1.ImageLoadStateLoaded ||
+		got.Message != "" || got.Attempts != 2 {
+		t.Errorf("preloadImages() status = %v, want Loaded after 2 attempts", got)
+	}
+
+	if want := []string{"nginx:1.21", "/images/app.tar"}; !reflect.DeepEqual(b.Images("test"), want) {
+		t.Errorf("preloadImages() loaded images = %v, want %v", b.Images("test"), want)
+	}
+
+	if !meta.IsStatusConditionTrue(kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition) {
+		t.Errorf("preloadImages() conditions = %v, want ImagesPreloaded True", kindcluster.Status.Conditions)
+	}
+
+	// The images are loaded again into a node that was added later
+	if _, err := b.CreateNode("test", backend.NodeOptions{Name: "test-worker", Role: "worker"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if loading, _ := preload(); !loading {
+		t.Fatalf("preloadImages() loading = %v after a node was added, want true", loading)
+	}
+
+	if loading, failed := preload(); loading || failed {
+		t.Fatalf("preloadImages() = %v, %v, want false, false", loading, failed)
+	}
+
+	if got := b.Images("test"); len(got) != 4 {
+		t.Errorf("preloadImages() loaded images = %v, want the images loaded again", got)
+	}
+
+	// The statuses and the condition of the removed images are dropped
+	kindcluster.Spec.PreloadImages = nil
+
+	preload()
+
+	if len(kindcluster.Status.PreloadedImages) != 0 ||
+		meta.FindStatusCondition(kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition) != nil {
+		t.Errorf("preloadImages() status = %v, conditions = %v, want empty",
+			kindcluster.Status.PreloadedImages, kindcluster.Status.Conditions)
+	}
+}
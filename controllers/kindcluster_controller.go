@@ -18,20 +18,29 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
@@ -42,44 +51,81 @@ const (
 	secretNameKey     = "secretName"
 	k8sVersionNameKey = "k8sversion"
 
-	// Kubeconfig output from the kind tool is stored in a temporary file
-	// This constant represents the template path of the temporary config file
-	configFilePathTemplate = "/tmp/%s-config"
-)
+	// Type of the background operation that creates a cluster
+	operationTypeCreate = "Create"
 
-// version - image tag map
-var k8sVersionImages = map[string]string{
-	"1.22": "kindest/node:v1.22.0",
-	"1.21": "kindest/node:v1.21.1",
-	"1.20": "kindest/node:v1.20.7",
-	"1.19": "kindest/node:v1.19.11",
-	"1.18": "kindest/node:v1.18.19",
-	"1.17": "kindest/node:v1.17.17",
-	"1.16": "kindest/node:v1.16.15",
-	"1.15": "kindest/node:v1.15.12",
-	"1.14": "kindest/node:v1.14.10",
-}
+	// The progress of a background operation is polled with this interval
+	operationPollInterval = 5 * time.Second
+
+	// A cluster whose host ports are used by another cluster is checked again with this
+	// interval, the ports may be released when the other cluster is deleted
+	portConflictRetryInterval = 30 * time.Second
+
+	// The preload images that failed to load into a cluster are retried with this interval
+	imageLoadRetryInterval = 30 * time.Second
+
+	// An addon that failed to apply is retried with this interval, the addons after it wait
+	addonRetryInterval = 30 * time.Second
+)
 
 // KINDClusterReconciler reconciles a KINDCluster object
 type KINDClusterReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+
+	// Backend creates, deletes and lists the workload clusters
+	Backend backend.ClusterBackend
+
+	// MaxConcurrentReconciles is the maximum number of KINDClusters that are reconciled in parallel
+	MaxConcurrentReconciles int
+
+	// LegacyKubeconfigSecret enables the <clusterName>-config secret with the config key,
+	// which was the only kubeconfig secret before the Cluster API format was supported
+	LegacyKubeconfigSecret bool
+
+	// PortAllocator allocates the API server and ingress host ports of the clusters from a
+	// range, kind picks random ports if it is not set
+	PortAllocator *PortAllocator
+
+	// Tracks the clusters that are being created in the background
+	operations operationTracker
+
+	// ProbeClusterHealth enables probing the API servers and the nodes of the provisioned
+	// clusters, a cluster is ready only if it is healthy
+	// The clusters are ready when they exist if it is not enabled.
+	ProbeClusterHealth bool
+
+	// HealthCheckInterval is the interval with which the provisioned clusters are reconciled
+	// again to probe their health, they are only probed when they change if it is zero
+	HealthCheckInterval time.Duration
+
+	// Connects to the workload clusters to apply the addons and to probe their health, the
+	// clusters are connected through their API servers if it is not set
+	newWorkloadCluster workloadClusterFactory
+
+	// The connections to the workload clusters
+	workloadClusters workloadClusterCache
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindclusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindimagecatalogs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	// Define the Logger instance of the Reconciler by using the request and the name of Reconciler
-	r.Log = ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster, req.NamespacedName)
+	// Define the Logger instance of the request by using the name of Reconciler
+	// It is not stored in the Reconciler because the requests can be reconciled in parallel
+	log := r.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster, req.NamespacedName)
 	ctx = context.Background()
 
 	// Reconciliation starts
-	r.Log.Info("Reconciling")
+	log.Info("Reconciling")
 
 	// Try to read the KINDCluster instance
 	var kindcluster infrastructurev1alpha1.KINDCluster
@@ -87,30 +133,71 @@ func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err := r.Client.Get(ctx, req.NamespacedName, &kindcluster); err != nil {
 		// If the error type is not "IsNotFound", then return error
 		if !k8serrors.IsNotFound(err) {
-			r.Log.Error(err, "unable to fetch KINDCluster instance")
+			log.Error(err, "unable to fetch KINDCluster instance")
 
 			return ctrl.Result{}, err
 		}
 
 		// If the error type is "IsNotFound", log the information and do not return error
-		r.Log.Info("KINDCluster resources cannot be found")
+		log.Info("KINDCluster resources cannot be found")
+
+		return ctrl.Result{}, nil
+	}
+
+	// Load the image catalog, the node images of the Kubernetes versions are resolved from it
+	catalog, err := infrastructurev1alpha1.LoadImageCatalog(ctx, r.Client)
+
+	if err != nil {
+		log.Error(err, "unable to fetch image catalogs")
+
+		return ctrl.Result{}, err
+	}
+
+	// Apply the defaults of the defaulting webhook, the webhooks can be disabled, for example
+	// when the controller runs locally. The defaults are patched into the spec, so that the
+	// pinned node image and the cluster name do not change in the next reconciliations.
+	undefaulted := kindcluster.DeepCopy()
+	kindcluster.SetDefaults(catalog)
+
+	if !equality.Semantic.DeepEqual(undefaulted.Spec, kindcluster.Spec) {
+		if err := r.Patch(ctx, &kindcluster, client.MergeFrom(undefaulted)); err != nil {
+			log.Error(err, "unable to store defaults of KINDCluster")
+
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Defaults are stored in spec")
+	}
+
+	// Fetch the Cluster API Cluster that owns the KINDCluster, the KINDCluster can
+	// also be used without Cluster API, so the owner is optional
+	ownerCluster, err := getOwnerCluster(ctx, r.Client, &kindcluster)
+
+	if err != nil {
+		log.Error(err, "unable to fetch owner Cluster")
+
+		return ctrl.Result{}, err
+	}
+
+	// Do not reconcile if the owner Cluster or the KINDCluster is paused
+	if isPaused(ownerCluster, &kindcluster) {
+		log.Info("Reconciliation is paused")
 
 		return ctrl.Result{}, nil
 	}
 
-	// Create a provider object to create, delete, list the clusters
-	provider := cluster.NewProvider()
-	clusterList, err := provider.List()
+	// List the existing clusters by using the backend
+	clusterList, err := r.Backend.List()
 
 	if err != nil {
-		r.Log.Error(err, "unable to fetch clusters")
+		log.Error(err, "unable to fetch clusters")
 
 		return ctrl.Result{}, err
 	}
 
 	// Read the cluster name from the spec of KINDCluster instance
 	clusterName := kindcluster.Spec.ClusterName
-	kubernetesVersion := kindcluster.Spec.KubernetesVersion
+	capiClusterName := getCAPIClusterName(&kindcluster, ownerCluster)
 
 	// Check DeletionTimestamp to decide if object is in deletion
 	if kindcluster.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -120,12 +207,22 @@ func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			controllerutil.AddFinalizer(&kindcluster, finalizerName)
 
 			if err := r.Update(ctx, &kindcluster); err != nil {
-				r.Log.Error(err, "unable to add finalizer")
+				log.Error(err, "unable to add finalizer")
 
 				return ctrl.Result{}, err
 			}
 
-			r.Log.Info("Finalizer successfully added")
+			log.Info("Finalizer successfully added")
+
+			// The KINDCluster is accepted, the creation starts in the next reconciliation
+			kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhasePending
+			kindcluster.Status.ObservedGeneration = kindcluster.Generation
+
+			if err := r.Client.Status().Update(ctx, &kindcluster); err != nil {
+				log.Error(err, "unable to update KINDCluster status")
+
+				return ctrl.Result{}, err
+			}
 
 			return ctrl.Result{}, nil
 		}
@@ -133,11 +230,49 @@ func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// Object is in deletion, so check the finalizer and delete the related resources,
 		// cluster and config secret
 		if containsString(finalizerName, kindcluster.GetFinalizers()) {
-			if err := deleteCluster(provider, clusterName, r.Log); err != nil {
+			if kindcluster.Status.Phase != infrastructurev1alpha1.KINDClusterPhaseDeleting {
+				kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhaseDeleting
+
+				if err := r.Client.Status().Update(ctx, &kindcluster); err != nil {
+					log.Error(err, "unable to update KINDCluster status")
+
+					return ctrl.Result{}, err
+				}
+			}
+
+			// Wait for the background creation to finish before deleting the cluster
+			if op, ok := r.operations.get(clusterName); ok {
+				if !op.Done {
+					log.Info("Specified cluster is being created, deletion is waiting...", clusterNameKey, clusterName)
+
+					return ctrl.Result{RequeueAfter: operationPollInterval}, nil
+				}
+
+				r.operations.remove(clusterName)
+			}
+
+			// The results of the image loads into the deleted cluster are not recorded
+			for _, image := range kindcluster.Spec.PreloadImages {
+				r.operations.remove(getImageLoadOperationKey(clusterName, image))
+			}
+
+			if err := deleteCluster(r.Backend, clusterName, log); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			r.workloadClusters.remove(clusterName)
+
+			// The host ports of the deleted cluster can be allocated to other clusters
+			if r.PortAllocator != nil {
+				r.PortAllocator.release(req.NamespacedName.String())
+			}
+
+			if err := deleteConfigSecret(r.Client, log, clusterName, req.Namespace); err != nil {
 				return ctrl.Result{}, err
 			}
 
-			if err := deleteConfigSecret(r.Client, r.Log, clusterName, req.Namespace); err != nil {
+			if err := deleteKubeconfigSecret(r.Client, log, getStoredKubeconfigSecretName(&kindcluster, capiClusterName),
+				req.Namespace); err != nil {
 				return ctrl.Result{}, err
 			}
 
@@ -145,7 +280,7 @@ func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			controllerutil.RemoveFinalizer(&kindcluster, finalizerName)
 
 			if err := r.Client.Update(ctx, &kindcluster); err != nil {
-				r.Log.Error(err, "unable to update KINDCluster")
+				log.Error(err, "unable to update KINDCluster")
 
 				return ctrl.Result{}, err
 			}
@@ -154,62 +289,335 @@ func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
-	var creationError error
+	// The errors of the creation, of the kubeconfig secrets and of the registry are returned
+	// after they are reported in the status
+	var creationError, kubeconfigError, registryError, registryAuthError error
 
-	// Check if the specified cluster exists
-	if containsString(clusterName, clusterList) {
-		// Cluster exists
-		r.Log.Info("Specified cluster exists", clusterNameKey, clusterName)
+	provisioning, portConflict, imagesLoading, imageLoadFailed, addonFailed := false, false, false, false, false
 
-		trueBool := true
+	// The health of a provisioned cluster is probed again after the health check interval
+	healthCheck := false
+
+	conditions := clusterConditions(&kindcluster)
+
+	// Report whether the version is supported, a cluster of an unsupported version is not created
+	versionSupported := setKubernetesVersionCondition(&kindcluster, catalog)
+
+	// Get the kind cluster configuration, an invalid raw configuration is reported in the
+	// status and the cluster is not created until it is fixed
+	kindConfig, err := getKindConfig(ctx, r.Client, &kindcluster)
+
+	var configError *kindConfigError
+
+	if errors.As(err, &configError) {
+		log.Info("Specified kind configuration is not valid", clusterNameKey, clusterName, "reason", configError.reason)
+
+		// The running cluster is still compared with the typed fields of the spec
+		kindConfig = buildKindConfig(&kindcluster)
+	} else if err != nil {
+		log.Error(err, "unable to fetch kind configuration")
+
+		return ctrl.Result{}, err
+	}
+
+	setKindConfigCondition(&kindcluster, configError)
+
+	if versionSupported {
+		kindcluster.Status.NodeImages = getNodeImages(kindConfig)
+	} else {
+		kindcluster.Status.NodeImages = nil
+	}
+
+	// The host ports that were allocated to the cluster are added to its configuration
+	clusterConfig := withPortAllocations(kindConfig, &kindcluster)
+
+	// The operations are not tracked across restarts of the controller, so an operation that
+	// is reported in the status but is not tracked anymore is cleared, and the cluster is
+	// observed as it is listed by the backend
+	if _, ok := r.operations.get(clusterName); !ok && kindcluster.Status.Operation != nil {
+		log.Info("Operation of cluster is not tracked anymore, it is cleared", clusterNameKey, clusterName,
+			"operation", kindcluster.Status.Operation.Type)
+
+		kindcluster.Status.Operation = nil
+	}
+
+	// Check if the creation of the specified cluster is tracked, the cluster may already
+	// be listed while it is being created, so the operation is checked first
+	if op, ok := r.operations.get(clusterName); ok {
+		provisioning, creationError = r.recordCreation(&kindcluster, op, log)
+	} else if containsString(clusterName, clusterList) {
+		// Cluster exists
+		log.Info("Specified cluster exists", clusterNameKey, clusterName)
 
-		// Set the failureMessage to empty string and the ready bool to true
+		// Set the failureMessage to empty string, the ready bool is set from the Ready
+		// condition after the health of the cluster is probed
 		kindcluster.Status.FailureMessage = ""
-		kindcluster.Status.Ready = &trueBool
+		kindcluster.Status.FailureReason = ""
+		kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhaseProvisioned
+
+		conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionTrue,
+			reasonProvisioned, "Cluster is provisioned")
+
+		// Report the actual nodes of the cluster
+		nodes, err := r.Backend.ListNodes(clusterName)
+
+		if err != nil {
+			log.Error(err, "unable to list nodes of cluster")
+
+			return ctrl.Result{}, err
+		}
+
+		kindcluster.Status.Nodes = getNodeStatuses(nodes)
+		kindcluster.Status.NodeCount = countKubernetesNodes(nodes)
+
+		// Detect whether the running cluster still matches the spec
+		var drift []string
+
+		if kindcluster.Spec.DriftPolicy != infrastructurev1alpha1.DriftPolicyIgnore {
+			drift = detectDrift(&kindcluster, clusterConfig, nodes)
+		}
+
+		switch {
+		case kindcluster.Spec.DriftPolicy == infrastructurev1alpha1.DriftPolicyIgnore:
+			// The nodes are not compared with the spec, so whether they match it is not known
+			conditions.set(infrastructurev1alpha1.NodesHealthyCondition, metav1.ConditionUnknown,
+				reasonDriftDetectionDisabled, "Drift detection is disabled by the drift policy")
+		case len(drift) > 0:
+			log.Info("Specified cluster does not match the spec", clusterNameKey, clusterName)
+
+			conditions.set(infrastructurev1alpha1.NodesHealthyCondition, metav1.ConditionFalse,
+				reasonDrifted, strings.Join(drift, "; "))
+		default:
+			conditions.set(infrastructurev1alpha1.NodesHealthyCondition, metav1.ConditionTrue,
+				reasonNodesMatchSpec, "Nodes match the spec")
+		}
+
+		kindcluster.Status.Drift = drift
+
+		if len(drift) > 0 && kindcluster.Spec.DriftPolicy == infrastructurev1alpha1.DriftPolicyRecreate {
+			return r.recreateCluster(ctx, &kindcluster, capiClusterName, log)
+		}
+
+		// Report the effective networking options, the API server port is read from
+		// the kubeconfig because kind picks a random port if it is not specified
+		networking := getEffectiveNetworking(clusterConfig)
+
+		// Get the kubeconfigs of the cluster from the backend on every reconciliation, so
+		// that the secrets are created even if the cluster was not created by this
+		// controller instance, and they are updated if the cluster was recreated
+		currentKubeconfigs, err := getKubeconfigs(r.Backend, clusterName)
+
+		if err != nil {
+			log.Error(err, "unable to get kubeconfig of cluster")
+
+			return ctrl.Result{}, err
+		}
+
+		kindcluster.Status.KubeconfigHash = hashKubeconfig(currentKubeconfigs.External)
+
+		if networking.APIServerPort == 0 {
+			if networking.APIServerPort, err = getAPIServerPort(string(currentKubeconfigs.External)); err != nil {
+				log.Error(err, "unable to read API server port from kubeconfig")
+
+				return ctrl.Result{}, err
+			}
+		}
+
+		kindcluster.Status.Networking = networking
+		kindcluster.Status.Ports = getPortStatuses(clusterConfig)
+
+		// Set the control plane endpoint before the cluster is reported as ready,
+		// as required by the Cluster API infrastructure cluster contract
+		if kindcluster.Spec.ControlPlaneEndpoint.IsZero() {
+			// Updating the object overwrites the status in memory, so it is restored
+			status := kindcluster.Status.DeepCopy()
+
+			kindcluster.Spec.ControlPlaneEndpoint = infrastructurev1alpha1.APIEndpoint{
+				Host: networking.APIServerAddress,
+				Port: networking.APIServerPort,
+			}
+
+			if err := r.Update(ctx, &kindcluster); err != nil {
+				log.Error(err, "unable to set control plane endpoint")
+
+				return ctrl.Result{}, err
+			}
+
+			kindcluster.Status = *status
+		}
+
+		// Store the kubeconfigs in the secrets, an error is reported in the status
+		// and returned after the status is updated
+		if kubeconfigError = r.storeKubeconfigs(&kindcluster, capiClusterName, currentKubeconfigs, log); kubeconfigError != nil {
+			log.Error(kubeconfigError, "unable to store kubeconfig")
+
+			conditions.set(infrastructurev1alpha1.KubeconfigAvailableCondition, metav1.ConditionFalse,
+				reasonSecretError, kubeconfigError.Error())
+		} else {
+			conditions.set(infrastructurev1alpha1.KubeconfigAvailableCondition, metav1.ConditionTrue,
+				reasonSecretsStored, fmt.Sprintf("Kubeconfig is stored in secret %s", getKubeconfigSecretName(capiClusterName)))
+		}
+
+		// Ensure the local registry of the cluster, an error is reported in the status
+		// and returned after the status is updated
+		if kindcluster.Spec.Registry == nil {
+			kindcluster.Status.Registry = nil
+			meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.RegistryAvailableCondition)
+		} else if registryError = ensureRegistry(r.Backend, &kindcluster, log); registryError != nil {
+			log.Error(registryError, "unable to ensure registry of cluster")
+
+			conditions.set(infrastructurev1alpha1.RegistryAvailableCondition, metav1.ConditionFalse,
+				reasonRegistryError, registryError.Error())
+		} else {
+			conditions.set(infrastructurev1alpha1.RegistryAvailableCondition, metav1.ConditionTrue,
+				reasonRegistryAvailable, fmt.Sprintf("Registry is available at %s", kindcluster.Status.Registry.Endpoint))
+		}
+
+		// Write the registry credentials into the nodes, an error is reported in the status
+		// and returned after the status is updated
+		if registryAuthError = ensureRegistryAuth(ctx, r.Client, r.Backend, &kindcluster, nodes, log); registryAuthError != nil {
+			// The error does not contain the credentials, only the name of the Secret
+			log.Error(registryAuthError, "unable to write registry credentials into nodes of cluster")
+		}
+
+		// Load the preload images into the nodes in the background, the images that fail to
+		// load are retried later without recreating the cluster
+		imagesLoading, imageLoadFailed = preloadImages(r.Backend, &r.operations, &kindcluster, nodes, log)
+
+		// Apply the addons into the cluster with the kubeconfig that is stored in the secrets,
+		// they wait until the kubeconfig is available
+		if kubeconfigError == nil {
+			if addonFailed, err = r.applyAddons(ctx, &kindcluster, currentKubeconfigs.External, log); err != nil {
+				log.Error(err, "unable to read addons of cluster")
+
+				return ctrl.Result{}, err
+			}
+		}
+
+		// Probe the API server and the nodes, the cluster is ready only if it is healthy
+		if r.ProbeClusterHealth {
+			r.probeClusterHealth(ctx, &kindcluster, currentKubeconfigs.External, log)
+		} else {
+			meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.NodesReadyCondition)
+		}
+
+		setClusterReadyCondition(&kindcluster)
+
+		ready := meta.IsStatusConditionTrue(kindcluster.Status.Conditions, infrastructurev1alpha1.ReadyCondition)
+		kindcluster.Status.Ready = &ready
+		healthCheck = r.ProbeClusterHealth && r.HealthCheckInterval > 0
+	} else if !versionSupported {
+		// Cluster does not exist and cannot be created until the version is added to a catalog
+		log.Info("Specified kubernetes version is not supported", clusterNameKey, clusterName,
+			k8sVersionNameKey, kindcluster.Spec.KubernetesVersion)
+
+		kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhasePending
+
+		conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+			reasonUnsupportedVersion, "Cluster cannot be created with an unsupported kubernetes version")
+		setClusterReadyCondition(&kindcluster)
+	} else if configError != nil {
+		// Cluster does not exist and cannot be created until the kind configuration is fixed
+		kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhasePending
+
+		conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+			configError.reason, fmt.Sprintf("Cluster cannot be created with the kind configuration: %s", configError.message))
+		setClusterReadyCondition(&kindcluster)
 	} else {
-		// Cluster does not exist
-		r.Log.Info("Specified cluster does not exist, will be created...", clusterNameKey, clusterName)
-
-		// Create the kind cluster
-		if creationError = provider.Create(clusterName,
-			cluster.CreateWithKubeconfigPath(getConfigFilePath(clusterName)),
-			cluster.CreateWithNodeImage(k8sVersionImages[kubernetesVersion])); creationError != nil {
-			r.Log.Error(creationError, "unable to create cluster")
-
-			falseBool := false
-
-			// If an issue occurs while creation, then add a status condition
-			kindcluster.Status.Conditions = append(kindcluster.Status.Conditions,
-				infrastructurev1alpha1.KindClusterCondition{
-					Timestamp: metav1.Now(),
-					Message:   "Cluster cannot be created",
-					Reason:    creationError.Error(),
-				})
+		// Cluster does not exist, allocate its host ports from the range of the controller
+		// and check that its host ports are not used by another cluster before it is created,
+		// kind would fail late in the creation otherwise
+		var portConflicts []string
+
+		if r.PortAllocator != nil {
+			allocated, err := r.PortAllocator.allocate(ctx, r.Client, &kindcluster, kindConfig)
+
+			switch {
+			case errors.Is(err, errPortRangeExhausted):
+				portConflicts = append(portConflicts, err.Error())
+			case err != nil:
+				log.Error(err, "unable to allocate host ports of cluster")
+
+				return ctrl.Result{}, err
+			default:
+				kindcluster.Status.AllocatedPorts = allocated
+				clusterConfig = withPortAllocations(kindConfig, &kindcluster)
+			}
+		}
+
+		if len(portConflicts) == 0 {
+			if portConflicts, err = findHostPortConflicts(ctx, r.Client, &kindcluster, clusterConfig); err != nil {
+				log.Error(err, "unable to check host ports of cluster")
+
+				return ctrl.Result{}, err
+			}
+		}
+
+		if len(portConflicts) > 0 {
+			log.Info("Specified cluster has host port conflicts", clusterNameKey, clusterName)
+
+			portConflict = true
+			kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhasePending
 
-			// If an issue occurs while creation, set the failure message and the ready
-			// bool to false
-			kindcluster.Status.FailureMessage = fmt.Sprintf("Cluster cannot be crated: %s", creationError)
-			kindcluster.Status.Ready = &falseBool
+			conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+				reasonHostPortConflict, strings.Join(portConflicts, "; "))
+			setClusterReadyCondition(&kindcluster)
 		} else {
-			// If cluster was successfully created, then add a status condition
-			kindcluster.Status.Conditions = append(kindcluster.Status.Conditions,
-				infrastructurev1alpha1.KindClusterCondition{
-					Timestamp: metav1.Now(),
-					Message:   "Cluster was successfully created",
-				})
+			log.Info("Specified cluster does not exist, will be created...", clusterNameKey, clusterName)
+
+			// Create the kind cluster with the configuration of the spec in the background,
+			// the progress of the creation is polled by requeueing the request
+
+			config := clusterConfig
 
-			r.Log.Info("Specified cluster was successfully created", clusterNameKey, clusterName, k8sVersionNameKey, kubernetesVersion)
+			op := r.operations.start(clusterName, operationTypeCreate, func(setStep func(string)) error {
+				return r.Backend.Create(clusterName, config, backend.CreateOptions{
+					OnStep: setStep,
+				})
+			})
+
+			provisioning = true
+
+			kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhaseProvisioning
+			kindcluster.Status.Operation = getOperationStatus(op)
+			kindcluster.Status.Ports = getPortStatuses(config)
+
+			// The state of a previous cluster of the same name does not apply to the new
+			// cluster, for example if it was deleted outside of the controller or a failed
+			// creation is retried, so the registry is announced and the credentials, the images
+			// and the addons are delivered again
+			kindcluster.Status.Registry = nil
+			kindcluster.Status.RegistryAuthHash = ""
+			kindcluster.Status.PreloadedImages = nil
+			kindcluster.Status.Addons = nil
+			meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition)
+			meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.AddonsAppliedCondition)
+
+			conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+				reasonProvisioning, "Cluster is being created")
+			setClusterReadyCondition(&kindcluster)
 		}
 	}
 
 	// Update status of KINDCluster
+	kindcluster.Status.ObservedGeneration = kindcluster.Generation
+
 	if err := r.Client.Status().Update(ctx, &kindcluster); err != nil {
-		r.Log.Error(err, "unable to update KINDCluster status")
+		log.Error(err, "unable to update KINDCluster status")
 
 		return ctrl.Result{}, err
 	}
 
-	r.Log.Info("KINDCluster status was updated", clusterNameKey, clusterName)
+	log.Info("KINDCluster status was updated", clusterNameKey, clusterName)
+
+	// If the cluster is being created, poll the progress of the creation later
+	if provisioning {
+		return ctrl.Result{RequeueAfter: operationPollInterval}, nil
+	}
+
+	if portConflict {
+		return ctrl.Result{RequeueAfter: portConflictRetryInterval}, nil
+	}
 
 	// If an error occured while the creation of cluster, return the error after the
 	// status subresource was updated
@@ -217,21 +625,144 @@ func (r *KINDClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, creationError
 	}
 
-	// Store the kubeconfig in  a secret
-	if err := storeKubeconfigInSecret(r.Client, clusterName,
-		getConfigSecretName(clusterName), req.Namespace, r.Log); err != nil {
+	if kubeconfigError != nil {
+		return ctrl.Result{}, kubeconfigError
+	}
+
+	if registryError != nil {
+		return ctrl.Result{}, registryError
+	}
 
-		r.Log.Error(err, "unable to store kubeconfig")
+	if registryAuthError != nil {
+		return ctrl.Result{}, registryAuthError
+	}
 
-		return ctrl.Result{}, err
+	// If an image is being loaded, poll the progress of the load later
+	if imagesLoading {
+		return ctrl.Result{RequeueAfter: operationPollInterval}, nil
+	}
+
+	if imageLoadFailed {
+		return ctrl.Result{RequeueAfter: imageLoadRetryInterval}, nil
+	}
+
+	if addonFailed {
+		return ctrl.Result{RequeueAfter: addonRetryInterval}, nil
 	}
 
 	// Reconciliation finishes
-	r.Log.Info("Reconciled")
+	log.Info("Reconciled")
+
+	// Probe the health of the cluster again later to keep the status current
+	if healthCheck {
+		return ctrl.Result{RequeueAfter: r.HealthCheckInterval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// Record the progress or the result of the creation operation of the cluster in the status
+// It returns true if the creation is still in progress
+func (r *KINDClusterReconciler) recordCreation(kindcluster *infrastructurev1alpha1.KINDCluster, op operation,
+	log logr.Logger) (bool, error) {
+	clusterName := kindcluster.Spec.ClusterName
+	conditions := clusterConditions(kindcluster)
+
+	if !op.Done {
+		log.Info("Specified cluster is being created...", clusterNameKey, clusterName, "step", op.Step)
+
+		kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhaseProvisioning
+		kindcluster.Status.Operation = getOperationStatus(op)
+
+		// The step is reported in the message, so it does not add an event to the history
+		message := "Cluster is being created"
+
+		if op.Step != "" {
+			message = fmt.Sprintf("%s: %s", message, op.Step)
+		}
+
+		conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+			reasonProvisioning, message)
+		setClusterReadyCondition(kindcluster)
+
+		return true, nil
+	}
+
+	// The result of the operation is recorded only once
+	r.operations.remove(clusterName)
+	kindcluster.Status.Operation = nil
+
+	if creationError := op.Err; creationError != nil {
+		log.Error(creationError, "unable to create cluster")
+
+		falseBool := false
+
+		// If an issue occurs while creation, then set the status conditions
+		conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse,
+			failureReasonCreateError, fmt.Sprintf("Cluster cannot be created: %s", creationError))
+		setClusterReadyCondition(kindcluster)
+
+		// If an issue occurs while creation, set the failure message and the ready
+		// bool to false
+		kindcluster.Status.FailureMessage = fmt.Sprintf("Cluster cannot be crated: %s", creationError)
+		kindcluster.Status.FailureReason = failureReasonCreateError
+		kindcluster.Status.Ready = &falseBool
+		kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhaseFailed
+
+		return false, creationError
+	}
+
+	// If cluster was successfully created, then set the status conditions, the cluster
+	// is ready when its kubeconfig is stored in the next reconciliation
+	conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionTrue,
+		reasonProvisioned, "Cluster is provisioned")
+	setClusterReadyCondition(kindcluster)
+
+	log.Info("Specified cluster was successfully created", clusterNameKey, clusterName,
+		k8sVersionNameKey, kindcluster.Spec.KubernetesVersion)
+
+	return false, nil
+}
+
+// Store the kubeconfigs of the cluster in the secret in the Cluster API format, and in
+// the legacy secret if it is enabled
+// The name of the secret is recorded in the status, the secret of the previous name is deleted
+// when the name of the Cluster API Cluster changes.
+func (r *KINDClusterReconciler) storeKubeconfigs(kindcluster *infrastructurev1alpha1.KINDCluster, capiClusterName string,
+	kubeconfigs kubeconfigs, log logr.Logger) error {
+	secretName := getKubeconfigSecretName(capiClusterName)
+
+	if stored := kindcluster.Status.KubeconfigSecretName; stored != "" && stored != secretName {
+		if err := deleteKubeconfigSecret(r.Client, log, stored, kindcluster.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if err := storeKubeconfigInCAPISecret(r.Client, r.Scheme, kindcluster, capiClusterName, kubeconfigs, log); err != nil {
+		return err
+	}
+
+	kindcluster.Status.KubeconfigSecretName = secretName
+
+	if !r.LegacyKubeconfigSecret {
+		return nil
+	}
+
+	clusterName := kindcluster.Spec.ClusterName
+
+	return storeKubeconfigInSecret(r.Client, clusterName, getConfigSecretName(clusterName),
+		kindcluster.Namespace, kubeconfigs, log)
+}
+
+// Get the status of the background operation
+func getOperationStatus(op operation) *infrastructurev1alpha1.KINDClusterOperation {
+	return &infrastructurev1alpha1.KINDClusterOperation{
+		Type:      op.Type,
+		StartTime: op.StartTime,
+		Step:      op.Step,
+	}
+}
+
 // Check whether a slice contains a specified string
 func containsString(s string, slice []string) bool {
 	for _, finalizer := range slice {
@@ -248,20 +779,137 @@ func getConfigSecretName(clusterName string) string {
 	return fmt.Sprintf("%s-%s", clusterName, "config")
 }
 
-// Get the kubeconfig file path from the clustername
-func getConfigFilePath(clusterName string) string {
-	return fmt.Sprintf(configFilePathTemplate, clusterName)
+// Recreate the drifted cluster: delete the cluster and its kubeconfig secrets, the cluster
+// is created with the current spec in the next reconciliation
+func (r *KINDClusterReconciler) recreateCluster(ctx context.Context, kindcluster *infrastructurev1alpha1.KINDCluster,
+	capiClusterName string, log logr.Logger) (ctrl.Result, error) {
+	clusterName := kindcluster.Spec.ClusterName
+	namespace := kindcluster.Namespace
+
+	log.Info("Specified cluster does not match the spec, will be recreated...", clusterNameKey, clusterName)
+
+	if err := deleteCluster(r.Backend, clusterName, log); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The kubeconfig of the new cluster is different, so the old secrets are deleted
+	if err := deleteConfigSecret(r.Client, log, clusterName, namespace); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := deleteKubeconfigSecret(r.Client, log, getStoredKubeconfigSecretName(kindcluster, capiClusterName),
+		namespace); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The new cluster may listen on another port, so the control plane endpoint
+	// is set again when the cluster is provisioned
+	if !kindcluster.Spec.ControlPlaneEndpoint.IsZero() {
+		status := kindcluster.Status.DeepCopy()
+
+		kindcluster.Spec.ControlPlaneEndpoint = infrastructurev1alpha1.APIEndpoint{}
+
+		if err := r.Update(ctx, kindcluster); err != nil {
+			log.Error(err, "unable to reset control plane endpoint")
+
+			return ctrl.Result{}, err
+		}
+
+		kindcluster.Status = *status
+	}
+
+	falseBool := false
+
+	conditions := clusterConditions(kindcluster)
+
+	conditions.set(infrastructurev1alpha1.ClusterProvisionedCondition, metav1.ConditionFalse, reasonRecreating,
+		fmt.Sprintf("Cluster was deleted to be recreated with the current spec: %s", strings.Join(kindcluster.Status.Drift, "; ")))
+	conditions.set(infrastructurev1alpha1.KubeconfigAvailableCondition, metav1.ConditionFalse, reasonRecreating,
+		"Kubeconfig secrets were deleted with the cluster")
+	setClusterReadyCondition(kindcluster)
+
+	kindcluster.Status.Ready = &falseBool
+	kindcluster.Status.Phase = infrastructurev1alpha1.KINDClusterPhasePending
+	kindcluster.Status.Nodes = nil
+	kindcluster.Status.NodeCount = 0
+	kindcluster.Status.Networking = nil
+	kindcluster.Status.Ports = nil
+	kindcluster.Status.Registry = nil
+	kindcluster.Status.RegistryAuthHash = ""
+	kindcluster.Status.PreloadedImages = nil
+	kindcluster.Status.Addons = nil
+	meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.ImagesPreloadedCondition)
+	meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.AddonsAppliedCondition)
+	meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.NodesReadyCondition)
+	kindcluster.Status.Drift = nil
+	kindcluster.Status.KubeconfigHash = ""
+	kindcluster.Status.KubeconfigSecretName = ""
+	kindcluster.Status.ObservedGeneration = kindcluster.Generation
+
+	if err := r.Client.Status().Update(ctx, kindcluster); err != nil {
+		log.Error(err, "unable to update KINDCluster status")
+
+		return ctrl.Result{}, err
+	}
+
+	// Requeue to create the cluster again
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// Get the status of the actual nodes of the cluster with their roles
+func getNodeStatuses(nodes []backend.Node) []infrastructurev1alpha1.KINDNodeStatus {
+	nodeStatuses := make([]infrastructurev1alpha1.KINDNodeStatus, 0, len(nodes))
+
+	for _, node := range nodes {
+		nodeStatuses = append(nodeStatuses, infrastructurev1alpha1.KINDNodeStatus{
+			Name: node.Name,
+			Role: node.Role,
+		})
+	}
+
+	// Sort the nodes to keep the status stable between reconciliations
+	sort.Slice(nodeStatuses, func(i, j int) bool {
+		return nodeStatuses[i].Name < nodeStatuses[j].Name
+	})
+
+	return nodeStatuses
+}
+
+// Count the nodes of the cluster that run Kubernetes
+func countKubernetesNodes(nodes []backend.Node) int32 {
+	var count int32
+
+	for _, node := range nodes {
+		if node.KubernetesVersion != "" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Get the sorted names of the nodes
+func getNodeNames(nodes []backend.Node) []string {
+	names := make([]string, 0, len(nodes))
+
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	sort.Strings(names)
+
+	return names
 }
 
 // Delete the external resources: kind cluster
-func deleteCluster(provider *cluster.Provider, clusterName string, log logr.Logger) error {
+func deleteCluster(clusterBackend backend.ClusterBackend, clusterName string, log logr.Logger) error {
 	log.Info("Cluster is deleting...", clusterNameKey, clusterName)
 
 	// Delete the kind cluster
 	// No check has been done as to whether the cluster already exists.
-	// Because the kind tool is idempotent and it does not return an error when it
+	// Because the backend is idempotent and it does not return an error when it
 	// cannot find the cluster.
-	if err := provider.Delete(clusterName, ""); err != nil {
+	if err := clusterBackend.Delete(clusterName); err != nil {
 		log.Error(err, "unable to delete cluster")
 
 		return err
@@ -294,10 +942,16 @@ func deleteConfigSecret(c client.Client, log logr.Logger, clusterName, namespace
 	return nil
 }
 
-// Store the kubeconfig of cluster in a secret
-func storeKubeconfigInSecret(c client.Client, clusterName, secretName, namespace string, log logr.Logger) error {
+// Store the kubeconfig of cluster in a secret, the secret is updated if the kubeconfig changed
+func storeKubeconfigInSecret(c client.Client, clusterName, secretName, namespace string, kubeconfigs kubeconfigs,
+	log logr.Logger) error {
 	kubeconfigSecret := &corev1.Secret{}
 
+	data := map[string][]byte{
+		legacyKubeconfigSecretKey:   kubeconfigs.External,
+		internalKubeconfigSecretKey: kubeconfigs.Internal,
+	}
+
 	// Try to get the config secret
 	if err := c.Get(context.Background(),
 		types.NamespacedName{
@@ -314,22 +968,13 @@ func storeKubeconfigInSecret(c client.Client, clusterName, secretName, namespace
 		// been created
 		// Start to create the config secret
 
-		// Read the kubeconfig from the temporary file
-		kubeconfigBody, err := ioutil.ReadFile(getConfigFilePath(clusterName))
-
-		if err != nil {
-			return err
-		}
-
 		// Create the secret object
 		kubeconfigSecret = &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      secretName,
 				Namespace: namespace,
 			},
-			Data: map[string][]byte{
-				"config": kubeconfigBody,
-			},
+			Data: data,
 		}
 
 		// Create the real secret object
@@ -338,17 +983,137 @@ func storeKubeconfigInSecret(c client.Client, clusterName, secretName, namespace
 		}
 
 		log.Info("Config secret successfully created", secretNameKey, secretName, clusterNameKey, clusterName)
+
+		return nil
+	}
+
+	// If err is nil, this means that the config secret was created earlier, so it is
+	// updated if the kubeconfig changed, for example when the cluster was recreated
+	updated, err := updateSecretData(c, kubeconfigSecret, data)
+
+	if err != nil {
+		return err
+	}
+
+	if updated {
+		log.Info("Config secret successfully updated", secretNameKey, secretName, clusterNameKey, clusterName)
 	}
 
-	// If err is nil, this means that the config secret was successfully created earlier,
-	// so do nothing and return nil
 	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *KINDClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Watch the KINDCluster instances to trigger the reconciler
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&infrastructurev1alpha1.KINDCluster{}).
-		Complete(r)
+		Owns(&corev1.Secret{}).
+		Watches(&source.Kind{Type: &infrastructurev1alpha1.KINDImageCatalog{}},
+			handler.EnqueueRequestsFromMapFunc(r.imageCatalogToKINDClusters)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.configMapToKINDClusters)).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.secretToKINDClusters)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles})
+
+	// Watch the Cluster API Clusters to react to the changes of the owners, for example
+	// when they are unpaused, only if Cluster API is installed in the management cluster
+	if _, err := mgr.GetRESTMapper().RESTMapping(capiClusterGVK.GroupKind(), capiClusterGVK.Version); err == nil {
+		capiCluster := &unstructured.Unstructured{}
+		capiCluster.SetGroupVersionKind(capiClusterGVK)
+
+		builder = builder.Watches(&source.Kind{Type: capiCluster},
+			handler.EnqueueRequestsFromMapFunc(clusterToKINDCluster))
+	} else {
+		r.Log.Info("Cluster API Cluster kind cannot be found, owner Clusters are not watched")
+	}
+
+	return builder.Complete(r)
+}
+
+// Map a KINDImageCatalog to all KINDClusters, a change of the catalog can change the
+// supported versions and the node images of any KINDCluster
+func (r *KINDClusterReconciler) imageCatalogToKINDClusters(o client.Object) []ctrl.Request {
+	var kindclusters infrastructurev1alpha1.KINDClusterList
+
+	if err := r.Client.List(context.Background(), &kindclusters); err != nil {
+		r.Log.Error(err, "unable to list KINDClusters", infrastructurev1alpha1.KindOfKindImageCatalog, o.GetName())
+
+		return nil
+	}
+
+	requests := make([]ctrl.Request, 0, len(kindclusters.Items))
+
+	for _, kindcluster := range kindclusters.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{
+			Name:      kindcluster.Name,
+			Namespace: kindcluster.Namespace,
+		}})
+	}
+
+	return requests
+}
+
+// Map a ConfigMap to the KINDClusters of its namespace that refer to it for their kind
+// cluster configuration or for the manifests of their addons
+func (r *KINDClusterReconciler) configMapToKINDClusters(o client.Object) []ctrl.Request {
+	return r.referringKINDClusters(o, "ConfigMap", func(kindcluster *infrastructurev1alpha1.KINDCluster) bool {
+		if ref := kindcluster.Spec.KindConfigRef; ref != nil && ref.Name == o.GetName() {
+			return true
+		}
+
+		for _, addon := range kindcluster.Spec.Addons {
+			if addon.ConfigMapRef != nil && addon.ConfigMapRef.Name == o.GetName() {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Map a Secret to the KINDClusters of its namespace that refer to it for their registry
+// credentials or for the manifests of their addons, the kubeconfig secrets that the
+// KINDClusters own are mapped by the owner
+func (r *KINDClusterReconciler) secretToKINDClusters(o client.Object) []ctrl.Request {
+	return r.referringKINDClusters(o, "Secret", func(kindcluster *infrastructurev1alpha1.KINDCluster) bool {
+		if ref := kindcluster.Spec.RegistryAuth; ref != nil && ref.SecretName == o.GetName() {
+			return true
+		}
+
+		for _, addon := range kindcluster.Spec.Addons {
+			if addon.SecretRef != nil && addon.SecretRef.Name == o.GetName() {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Map an object to the KINDClusters of its namespace that refer to it
+func (r *KINDClusterReconciler) referringKINDClusters(o client.Object, kind string,
+	refers func(*infrastructurev1alpha1.KINDCluster) bool) []ctrl.Request {
+	var kindclusters infrastructurev1alpha1.KINDClusterList
+
+	if err := r.Client.List(context.Background(), &kindclusters, client.InNamespace(o.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list KINDClusters", kind, client.ObjectKeyFromObject(o))
+
+		return nil
+	}
+
+	var requests []ctrl.Request
+
+	for i := range kindclusters.Items {
+		kindcluster := &kindclusters.Items[i]
+
+		if refers(kindcluster) {
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{
+				Name:      kindcluster.Name,
+				Namespace: kindcluster.Namespace,
+			}})
+		}
+	}
+
+	return requests
 }
@@ -2,20 +2,24 @@ package controllers
 
 import (
 	"context"
-	"fmt"
-	"io/ioutil"
-	"os"
 	"testing"
+	"time"
 
 	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 )
 
 var defaultNamespace = "default"
@@ -55,23 +59,6 @@ func Test_GetConfigSecretName(t *testing.T) {
 	}
 }
 
-func Test_GetConfigFilePath(t *testing.T) {
-	var testCases = []struct {
-		clusterName string
-		filePath    string
-	}{
-		{"test-cluster", "/tmp/test-cluster-config"},
-		{"", "/tmp/-config"},
-	}
-	for _, tc := range testCases {
-		t.Run(tc.clusterName, func(t *testing.T) {
-			if got := getConfigFilePath(tc.clusterName); got != tc.filePath {
-				t.Errorf("containsString() = %v, want %v", got, tc.filePath)
-			}
-		})
-	}
-}
-
 func Test_StoreKubeconfigInSecret(t *testing.T) {
 	c := fake.NewFakeClientWithScheme(scheme.Scheme, &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -81,7 +68,8 @@ func Test_StoreKubeconfigInSecret(t *testing.T) {
 
 	resultSecret := corev1.Secret{
 		Data: map[string][]byte{
-			"config": []byte("kubeconfigData"),
+			"config":   []byte("kubeconfigData"),
+			"internal": []byte("internalKubeconfigData"),
 		},
 	}
 
@@ -97,21 +85,9 @@ func Test_StoreKubeconfigInSecret(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.clusterName, func(t *testing.T) {
-			err := ioutil.WriteFile(fmt.Sprintf(getConfigFilePath(tc.clusterName)), []byte("kubeconfigData"), 0755)
+			kubeconfigs := kubeconfigs{External: []byte("kubeconfigData"), Internal: []byte("internalKubeconfigData")}
 
-			if err != nil {
-				fmt.Printf("Unable to write file: %v", err)
-			}
-
-			defer func() {
-				err := os.Remove(getConfigFilePath(tc.clusterName))
-
-				if err != nil {
-					panic(err)
-				}
-			}()
-
-			if err := storeKubeconfigInSecret(c, tc.clusterName, tc.secretName, tc.namespace, log); err != nil {
+			if err := storeKubeconfigInSecret(c, tc.clusterName, tc.secretName, tc.namespace, kubeconfigs, log); err != nil {
 				panic(err)
 			}
 
@@ -120,7 +96,8 @@ func Test_StoreKubeconfigInSecret(t *testing.T) {
 				panic(err)
 			}
 
-			if string(tc.result.Data["config"]) != string(secret.Data["config"]) {
+			if string(tc.result.Data["config"]) != string(secret.Data["config"]) ||
+				string(tc.result.Data["internal"]) != string(secret.Data["internal"]) {
 				t.Errorf("storeKubeconfigInSecret() = %v, want %v", tc.result.Data, secret.Data)
 			}
 		})
@@ -177,3 +154,485 @@ func Test_DeleteConfigSecret(t *testing.T) {
 		})
 	}
 }
+
+func Test_Reconcile(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-reconcile",
+			Namespace: defaultNamespace,
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-reconcile",
+			KubernetesVersion: "1.21",
+			Topology: &infrastructurev1alpha1.KINDClusterTopology{
+				ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1},
+				Workers:      infrastructurev1alpha1.WorkerTopology{Replicas: 2},
+			},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+	b := backend.NewFakeBackend()
+
+	workload := &fakeWorkloadCluster{nodes: []corev1.Node{
+		newWorkloadNode("test-reconcile-control-plane", corev1.ConditionTrue),
+		newWorkloadNode("test-reconcile-worker", corev1.ConditionTrue),
+		newWorkloadNode("test-reconcile-worker2", corev1.ConditionTrue),
+	}}
+
+	r := &KINDClusterReconciler{
+		Client:                 c,
+		Scheme:                 testScheme,
+		Log:                    ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend:                b,
+		LegacyKubeconfigSecret: true,
+		ProbeClusterHealth:     true,
+		HealthCheckInterval:    time.Minute,
+		newWorkloadCluster: func(kubeconfig []byte) (workloadCluster, error) {
+			return workload, nil
+		},
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	// The first reconciliation adds the finalizer and accepts the instance
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	pending := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, pending); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending.Status.Phase != infrastructurev1alpha1.KINDClusterPhasePending {
+		t.Errorf("Reconcile() phase = %v, want Pending", pending.Status.Phase)
+	}
+
+	// The second reconciliation starts the creation
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	provisioning := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, provisioning); err != nil {
+		t.Fatal(err)
+	}
+
+	if provisioning.Status.Phase != infrastructurev1alpha1.KINDClusterPhaseProvisioning || provisioning.Status.Operation == nil {
+		t.Errorf("Reconcile() phase = %v, operation = %v, want Provisioning with an operation",
+			provisioning.Status.Phase, provisioning.Status.Operation)
+	}
+
+	waitForOperation(t, &r.operations, kindcluster.Spec.ClusterName)
+
+	// The third reconciliation records the creation and the fourth one observes the existing cluster
+	var result ctrl.Result
+
+	for i := 0; i < 2; i++ {
+		var err error
+
+		if result, err = r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	// The health of the provisioned cluster is probed again after the interval
+	if result.RequeueAfter != r.HealthCheckInterval {
+		t.Errorf("Reconcile() requeueAfter = %v, want %v", result.RequeueAfter, r.HealthCheckInterval)
+	}
+
+	clusters, _ := b.List()
+
+	if !containsString(kindcluster.Spec.ClusterName, clusters) {
+		t.Errorf("Reconcile() clusters = %v, want %s", clusters, kindcluster.Spec.ClusterName)
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if reconciled.Status.Ready == nil || !*reconciled.Status.Ready {
+		t.Errorf("Reconcile() ready = %v, want true", reconciled.Status.Ready)
+	}
+
+	if reconciled.Status.Phase != infrastructurev1alpha1.KINDClusterPhaseProvisioned || reconciled.Status.Operation != nil {
+		t.Errorf("Reconcile() phase = %v, operation = %v, want Provisioned without an operation",
+			reconciled.Status.Phase, reconciled.Status.Operation)
+	}
+
+	if endpoint := reconciled.Spec.ControlPlaneEndpoint; endpoint.Host != "127.0.0.1" || endpoint.Port != 6443 {
+		t.Errorf("Reconcile() controlPlaneEndpoint = %+v, want 127.0.0.1:6443", endpoint)
+	}
+
+	if !meta.IsStatusConditionTrue(reconciled.Status.Conditions, infrastructurev1alpha1.NodesReadyCondition) ||
+		!meta.IsStatusConditionTrue(reconciled.Status.Conditions, infrastructurev1alpha1.ReadyCondition) ||
+		reconciled.Status.ObservedGeneration != reconciled.Generation {
+		t.Errorf("Reconcile() conditions = %v, observedGeneration = %d, want Ready for generation %d",
+			reconciled.Status.Conditions, reconciled.Status.ObservedGeneration, reconciled.Generation)
+	}
+
+	if image := reconciled.Status.NodeImages[string(v1alpha4.WorkerRole)]; image != defaults.Image {
+		t.Errorf("Reconcile() worker node image = %v, want %v", image, defaults.Image)
+	}
+
+	if reconciled.Status.KubeconfigHash == "" {
+		t.Errorf("Reconcile() kubeconfigHash is empty, want the hash of the kubeconfig")
+	}
+
+	if len(reconciled.Status.Nodes) != 3 || reconciled.Status.NodeCount != 3 {
+		t.Errorf("Reconcile() nodes = %v, nodeCount = %d, want 3 nodes", reconciled.Status.Nodes, reconciled.Status.NodeCount)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(context.Background(), types.NamespacedName{
+		Name:      getConfigSecretName(kindcluster.Spec.ClusterName),
+		Namespace: defaultNamespace,
+	}, secret); err != nil {
+		t.Errorf("Reconcile() kubeconfig secret error = %v", err)
+	}
+
+	if err := c.Get(context.Background(), types.NamespacedName{
+		Name:      getKubeconfigSecretName(kindcluster.Name),
+		Namespace: defaultNamespace,
+	}, secret); err != nil {
+		t.Errorf("Reconcile() Cluster API kubeconfig secret error = %v", err)
+	}
+
+	// Deleting the instance deletes the cluster and removes the finalizer
+	if err := c.Delete(context.Background(), reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	clusters, _ = b.List()
+
+	if containsString(kindcluster.Spec.ClusterName, clusters) {
+		t.Errorf("Reconcile() clusters = %v, want the cluster deleted", clusters)
+	}
+
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); !k8serrors.IsNotFound(err) {
+		t.Errorf("Reconcile() KINDCluster error = %v, want not found", err)
+	}
+}
+
+func Test_ReconcileUnsupportedVersion(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-unsupported",
+			Namespace:  defaultNamespace,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-unsupported",
+			KubernetesVersion: "1.99",
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+
+	r := &KINDClusterReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend: backend.NewFakeBackend(),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if _, ok := r.operations.get(kindcluster.Spec.ClusterName); ok {
+		t.Errorf("Reconcile() started the creation, want the cluster of an unsupported version not created")
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	condition := meta.FindStatusCondition(reconciled.Status.Conditions,
+		infrastructurev1alpha1.KubernetesVersionSupportedCondition)
+
+	if reconciled.Status.Phase != infrastructurev1alpha1.KINDClusterPhasePending || condition == nil ||
+		condition.Status != metav1.ConditionFalse {
+		t.Errorf("Reconcile() phase = %v, condition = %+v, want Pending with an unsupported version",
+			reconciled.Status.Phase, condition)
+	}
+}
+
+func Test_ReconcileDriftPolicyIgnore(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-ignore",
+			Namespace:  defaultNamespace,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-ignore",
+			KubernetesVersion: "1.21",
+			DriftPolicy:       infrastructurev1alpha1.DriftPolicyIgnore,
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+
+	r := &KINDClusterReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend: backend.NewFakeBackend(),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		waitForOperation(t, &r.operations, kindcluster.Spec.ClusterName)
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	// The nodes are not compared with the spec, so they are not reported as healthy
+	condition := meta.FindStatusCondition(reconciled.Status.Conditions, infrastructurev1alpha1.NodesHealthyCondition)
+
+	if condition == nil || condition.Status != metav1.ConditionUnknown || condition.Reason != reasonDriftDetectionDisabled {
+		t.Errorf("Reconcile() NodesHealthy condition = %+v, want Unknown with reason %s", condition, reasonDriftDetectionDisabled)
+	}
+}
+
+func Test_ReconcileStoresDefaults(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	// The KINDCluster was created while the webhooks were disabled, it is paused so that
+	// the reconciliation does not update it otherwise
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-defaults",
+			Namespace:   defaultNamespace,
+			Finalizers:  []string{finalizerName},
+			Annotations: map[string]string{pausedAnnotation: "true"},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+
+	r := &KINDClusterReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend: backend.NewFakeBackend(),
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec := reconciled.Spec; spec.ClusterName == "" || spec.KubernetesVersion == "" || spec.NodeImage == "" {
+		t.Errorf("Reconcile() spec = %+v, want the defaults stored", spec)
+	}
+}
+
+func Test_ReconcileCreateResetsClusterState(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	// The status of a cluster that was deleted outside of the controller
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-reset",
+			Namespace:  defaultNamespace,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-reset",
+			KubernetesVersion: "1.21",
+			PreloadImages:     []string{"nginx:1.21"},
+			Registry:          &infrastructurev1alpha1.KINDClusterRegistry{},
+			Addons: []infrastructurev1alpha1.KINDClusterAddon{
+				{Name: "cni", ConfigMapRef: &infrastructurev1alpha1.AddonSourceReference{Name: "cni"}},
+			},
+		},
+		Status: infrastructurev1alpha1.KINDClusterStatus{
+			Registry: getRegistryStatus(getRegistryOptions(&infrastructurev1alpha1.KINDClusterRegistry{})),
+			PreloadedImages: []infrastructurev1alpha1.KINDImageLoadStatus{
+				{Image: "nginx:1.21", State: infrastructurev1alpha1.ImageLoadStateLoaded, Attempts: 1},
+			},
+		},
+	}
+
+	cni := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cni", Namespace: defaultNamespace},
+		Data:       map[string]string{"cni.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: cni\n"},
+	}
+
+	// The addon was applied into the previous cluster
+	manifests, _ := getAddonManifests(context.Background(), fake.NewFakeClientWithScheme(testScheme, cni),
+		defaultNamespace, kindcluster.Spec.Addons[0])
+
+	kindcluster.Status.Addons = []infrastructurev1alpha1.KINDAddonStatus{{
+		Name:        "cni",
+		AppliedHash: hashAddonManifests(manifests),
+		Conditions: []metav1.Condition{{
+			Type:   infrastructurev1alpha1.AddonAppliedCondition,
+			Status: metav1.ConditionTrue,
+			Reason: reasonAddonApplied,
+		}},
+	}}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster, cni)
+	b := backend.NewFakeBackend()
+	workload := &fakeWorkloadCluster{}
+
+	r := &KINDClusterReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend: b,
+		newWorkloadCluster: func(kubeconfig []byte) (workloadCluster, error) {
+			return workload, nil
+		},
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	// The first reconciliation starts the creation, the second one records it, the third
+	// one observes the new cluster and the fourth one records the loaded images
+	for i := 0; i < 4; i++ {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		waitForOperation(t, &r.operations, kindcluster.Spec.ClusterName)
+		waitForOperation(t, &r.operations, getImageLoadOperationKey(kindcluster.Spec.ClusterName, "nginx:1.21"))
+	}
+
+	if manifests := b.Manifests(kindcluster.Spec.ClusterName); len(manifests) != 1 {
+		t.Errorf("Reconcile() applied manifests = %v, want the registry announced in the new cluster", manifests)
+	}
+
+	if images := b.Images(kindcluster.Spec.ClusterName); len(images) != 1 {
+		t.Errorf("Reconcile() loaded images = %v, want the preload images loaded into the new cluster", images)
+	}
+
+	if len(workload.applied) != 1 {
+		t.Errorf("Reconcile() applied = %v, want the addons applied into the new cluster", workload.applied)
+	}
+}
+
+func Test_ReconcileClearsUntrackedOperation(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	// The status of a cluster whose creation was tracked by a controller that restarted
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-restart",
+			Namespace:  defaultNamespace,
+			Finalizers: []string{finalizerName},
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-restart",
+			KubernetesVersion: "1.21",
+		},
+		Status: infrastructurev1alpha1.KINDClusterStatus{
+			Phase: infrastructurev1alpha1.KINDClusterPhaseProvisioning,
+			Operation: &infrastructurev1alpha1.KINDClusterOperation{
+				Type:      operationTypeCreate,
+				StartTime: metav1.Now(),
+				Step:      "Starting control-plane",
+			},
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+	b := backend.NewFakeBackend()
+
+	// The cluster is listed by the backend, but the new controller does not track its creation
+	if err := b.Create(kindcluster.Spec.ClusterName, nil, backend.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &KINDClusterReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster),
+		Backend: b,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindcluster.Name, Namespace: defaultNamespace}}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDCluster{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if reconciled.Status.Operation != nil || reconciled.Status.Phase != infrastructurev1alpha1.KINDClusterPhaseProvisioned {
+		t.Errorf("Reconcile() operation = %+v, phase = %v, want the stale operation cleared",
+			reconciled.Status.Operation, reconciled.Status.Phase)
+	}
+}
+
+func Test_CountKubernetesNodes(t *testing.T) {
+	nodes := []backend.Node{
+		{Name: "test-external-load-balancer", Role: "external-load-balancer"},
+		{Name: "test-control-plane", Role: "control-plane", KubernetesVersion: "v1.21.1"},
+		{Name: "test-worker", Role: "worker", KubernetesVersion: "v1.21.1"},
+	}
+
+	if got := countKubernetesNodes(nodes); got != 2 {
+		t.Errorf("countKubernetesNodes() = %d, want 2", got)
+	}
+}
+
+// Wait until the tracked background operation is done
+func waitForOperation(t *testing.T, operations *operationTracker, name string) {
+	for i := 0; i < 100; i++ {
+		if op, ok := operations.get(name); !ok || op.Done {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("operation of %s is not done", name)
+}
@@ -0,0 +1,447 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	yaml "gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+const (
+	kindConfigKind       = "Cluster"
+	kindConfigAPIVersion = "kind.x-k8s.io/v1alpha4"
+
+	// Key of the kind cluster configuration in the referenced ConfigMap if no key is specified
+	defaultKindConfigKey = "config"
+)
+
+// Build the kind cluster configuration from the spec of KINDCluster instance
+func buildKindConfig(kindcluster *infrastructurev1alpha1.KINDCluster) *v1alpha4.Cluster {
+	config := &v1alpha4.Cluster{
+		TypeMeta: v1alpha4.TypeMeta{
+			Kind:       kindConfigKind,
+			APIVersion: kindConfigAPIVersion,
+		},
+		Name: kindcluster.Spec.ClusterName,
+	}
+
+	// If the topology is not specified, the cluster consists of a single control-plane node
+	controlPlane := infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1}
+	workers := infrastructurev1alpha1.WorkerTopology{}
+
+	if topology := kindcluster.Spec.Topology; topology != nil {
+		controlPlane = topology.ControlPlane
+		workers = topology.Workers
+
+		// The replicas field is defaulted by the API server, but an object that
+		// was stored before the defaulting should still get a control-plane node
+		if controlPlane.Replicas < 1 {
+			controlPlane.Replicas = 1
+		}
+	}
+
+	for i := int32(0); i < controlPlane.Replicas; i++ {
+		config.Nodes = append(config.Nodes, buildKindNode(v1alpha4.ControlPlaneRole,
+			getNodeImage(kindcluster, v1alpha4.ControlPlaneRole), controlPlane.KINDNodeTemplate))
+	}
+
+	for i := int32(0); i < workers.Replicas; i++ {
+		config.Nodes = append(config.Nodes, buildKindNode(v1alpha4.WorkerRole,
+			getNodeImage(kindcluster, v1alpha4.WorkerRole), workers.KINDNodeTemplate))
+	}
+
+	if len(kindcluster.Spec.FeatureGates) > 0 {
+		config.FeatureGates = make(map[string]bool, len(kindcluster.Spec.FeatureGates))
+
+		for gate, enabled := range kindcluster.Spec.FeatureGates {
+			config.FeatureGates[gate] = enabled
+		}
+	}
+
+	if len(kindcluster.Spec.RuntimeConfig) > 0 {
+		config.RuntimeConfig = make(map[string]string, len(kindcluster.Spec.RuntimeConfig))
+
+		for key, value := range kindcluster.Spec.RuntimeConfig {
+			config.RuntimeConfig[key] = value
+		}
+	}
+
+	config.KubeadmConfigPatches = append(config.KubeadmConfigPatches, kindcluster.Spec.KubeadmConfigPatches...)
+
+	config.ContainerdConfigPatches = append(config.ContainerdConfigPatches, kindcluster.Spec.ContainerdConfigPatches...)
+
+	if registry := kindcluster.Spec.Registry; registry != nil {
+		config.ContainerdConfigPatches = append(config.ContainerdConfigPatches, getRegistryMirrorPatch(registry))
+	}
+
+	if networking := kindcluster.Spec.Networking; networking != nil {
+		config.Networking = v1alpha4.Networking{
+			IPFamily:          v1alpha4.ClusterIPFamily(networking.IPFamily),
+			APIServerAddress:  networking.APIServerAddress,
+			APIServerPort:     networking.APIServerPort,
+			PodSubnet:         networking.PodSubnet,
+			ServiceSubnet:     networking.ServiceSubnet,
+			DisableDefaultCNI: networking.DisableDefaultCNI,
+			KubeProxyMode:     v1alpha4.ProxyMode(networking.KubeProxyMode),
+		}
+	}
+
+	return config
+}
+
+// kindConfigError is an error of the kind cluster configuration of the spec, its reason
+// is reported in the KindConfigValid condition
+type kindConfigError struct {
+	reason  string
+	message string
+}
+
+func (e *kindConfigError) Error() string {
+	return e.message
+}
+
+// Load the raw kind cluster configuration of the KINDCluster instance, from the spec or
+// from the referenced ConfigMap
+// It returns an empty string if no configuration is specified.
+func loadKindConfig(ctx context.Context, c client.Reader, kindcluster *infrastructurev1alpha1.KINDCluster) (string, error) {
+	ref := kindcluster.Spec.KindConfigRef
+
+	if ref == nil {
+		return kindcluster.Spec.KindConfig, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: kindcluster.Namespace}, configMap); err != nil {
+		return "", err
+	}
+
+	key := ref.Key
+
+	if key == "" {
+		key = defaultKindConfigKey
+	}
+
+	raw, ok := configMap.Data[key]
+
+	if !ok {
+		return "", &kindConfigError{reason: reasonKindConfigNotFound,
+			message: fmt.Sprintf("ConfigMap %s does not have the key %s", ref.Name, key)}
+	}
+
+	return raw, nil
+}
+
+// Get the kind cluster configuration of the KINDCluster instance, the raw configuration of the
+// spec merged with the typed fields, or the configuration built from the typed fields only
+// A kindConfigError is returned if the raw configuration is invalid or cannot be found.
+func getKindConfig(ctx context.Context, c client.Reader, kindcluster *infrastructurev1alpha1.KINDCluster) (*v1alpha4.Cluster, error) {
+	raw, err := loadKindConfig(ctx, c, kindcluster)
+
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, &kindConfigError{reason: reasonKindConfigNotFound,
+				message: fmt.Sprintf("ConfigMap %s cannot be found", kindcluster.Spec.KindConfigRef.Name)}
+		}
+
+		return nil, err
+	}
+
+	if raw == "" {
+		return buildKindConfig(kindcluster), nil
+	}
+
+	config, err := parseKindConfig(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeKindConfig(config, kindcluster)
+}
+
+// Parse the raw kind cluster configuration with the types of kind, the unknown fields are
+// rejected in the same way as kind does
+func parseKindConfig(raw string) (*v1alpha4.Cluster, error) {
+	typeMeta := v1alpha4.TypeMeta{}
+
+	if err := yaml.Unmarshal([]byte(raw), &typeMeta); err != nil {
+		return nil, &kindConfigError{reason: reasonInvalidKindConfig,
+			message: fmt.Sprintf("kind configuration cannot be parsed: %s", err)}
+	}
+
+	if typeMeta.APIVersion != kindConfigAPIVersion || typeMeta.Kind != kindConfigKind {
+		return nil, &kindConfigError{reason: reasonInvalidKindConfig,
+			message: fmt.Sprintf("kind configuration must be a %s %s", kindConfigAPIVersion, kindConfigKind)}
+	}
+
+	config := &v1alpha4.Cluster{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(config); err != nil {
+		return nil, &kindConfigError{reason: reasonInvalidKindConfig,
+			message: fmt.Sprintf("kind configuration cannot be parsed: %s", err)}
+	}
+
+	return config, nil
+}
+
+// Merge the typed fields of the KINDCluster instance on top of the raw kind cluster configuration
+// The nodes of the raw configuration are used if the spec has no topology, they get the node
+// image of their role if they do not specify an image. A field that is set in both with
+// different values is a conflict.
+func mergeKindConfig(raw *v1alpha4.Cluster, kindcluster *infrastructurev1alpha1.KINDCluster) (*v1alpha4.Cluster, error) {
+	typed := buildKindConfig(kindcluster)
+	merged := raw.DeepCopy()
+
+	var conflicts []string
+
+	mergeString := func(path string, target *string, value string) {
+		switch {
+		case value == "":
+		case *target == "":
+			*target = value
+		case *target != value:
+			conflicts = append(conflicts, path)
+		}
+	}
+
+	mergeString("name", &merged.Name, typed.Name)
+
+	switch {
+	case len(merged.Nodes) == 0:
+		merged.Nodes = typed.Nodes
+	case kindcluster.Spec.Topology != nil:
+		conflicts = append(conflicts, "nodes")
+	default:
+		for i := range merged.Nodes {
+			if merged.Nodes[i].Image == "" {
+				merged.Nodes[i].Image = getNodeImage(kindcluster, merged.Nodes[i].Role)
+			}
+		}
+	}
+
+	if kindcluster.Spec.Networking != nil {
+		networking := &merged.Networking
+
+		ipFamily := string(networking.IPFamily)
+		mergeString("networking.ipFamily", &ipFamily, string(typed.Networking.IPFamily))
+		networking.IPFamily = v1alpha4.ClusterIPFamily(ipFamily)
+
+		mergeString("networking.apiServerAddress", &networking.APIServerAddress, typed.Networking.APIServerAddress)
+
+		switch {
+		case typed.Networking.APIServerPort == 0:
+		case networking.APIServerPort == 0:
+			networking.APIServerPort = typed.Networking.APIServerPort
+		case networking.APIServerPort != typed.Networking.APIServerPort:
+			conflicts = append(conflicts, "networking.apiServerPort")
+		}
+
+		mergeString("networking.podSubnet", &networking.PodSubnet, typed.Networking.PodSubnet)
+		mergeString("networking.serviceSubnet", &networking.ServiceSubnet, typed.Networking.ServiceSubnet)
+
+		kubeProxyMode := string(networking.KubeProxyMode)
+		mergeString("networking.kubeProxyMode", &kubeProxyMode, string(typed.Networking.KubeProxyMode))
+		networking.KubeProxyMode = v1alpha4.ProxyMode(kubeProxyMode)
+
+		networking.DisableDefaultCNI = networking.DisableDefaultCNI || typed.Networking.DisableDefaultCNI
+	}
+
+	// The feature gates and the runtime config are merged per key, the kubeadm and containerd
+	// config patches of the spec are applied after the patches of the raw configuration
+	for gate, enabled := range typed.FeatureGates {
+		if current, ok := merged.FeatureGates[gate]; ok && current != enabled {
+			conflicts = append(conflicts, "featureGates."+gate)
+
+			continue
+		}
+
+		if merged.FeatureGates == nil {
+			merged.FeatureGates = map[string]bool{}
+		}
+
+		merged.FeatureGates[gate] = enabled
+	}
+
+	for key, value := range typed.RuntimeConfig {
+		if current, ok := merged.RuntimeConfig[key]; ok && current != value {
+			conflicts = append(conflicts, "runtimeConfig."+key)
+
+			continue
+		}
+
+		if merged.RuntimeConfig == nil {
+			merged.RuntimeConfig = map[string]string{}
+		}
+
+		merged.RuntimeConfig[key] = value
+	}
+
+	merged.KubeadmConfigPatches = append(merged.KubeadmConfigPatches, typed.KubeadmConfigPatches...)
+	merged.ContainerdConfigPatches = append(merged.ContainerdConfigPatches, typed.ContainerdConfigPatches...)
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+
+		return nil, &kindConfigError{reason: reasonKindConfigConflict,
+			message: fmt.Sprintf("kind configuration conflicts with the spec in: %s", strings.Join(conflicts, ", "))}
+	}
+
+	merged.TypeMeta = typed.TypeMeta
+
+	return merged, nil
+}
+
+// Get the node image of a role of the KINDCluster instance, the image of the role in the
+// topology takes precedence over the image of the cluster, and the built-in image of the
+// Kubernetes version is used if no image is specified
+// The image of the cluster is resolved from the image catalog when the defaults are set.
+func getNodeImage(kindcluster *infrastructurev1alpha1.KINDCluster, role v1alpha4.NodeRole) string {
+	if topology := kindcluster.Spec.Topology; topology != nil {
+		switch {
+		case role == v1alpha4.ControlPlaneRole && topology.ControlPlane.NodeImage != "":
+			return topology.ControlPlane.NodeImage
+		case role == v1alpha4.WorkerRole && topology.Workers.NodeImage != "":
+			return topology.Workers.NodeImage
+		}
+	}
+
+	if kindcluster.Spec.NodeImage != "" {
+		return kindcluster.Spec.NodeImage
+	}
+
+	image, _ := infrastructurev1alpha1.NewImageCatalog(nil).Image(kindcluster.Spec.KubernetesVersion)
+
+	return image
+}
+
+// Get the effective node images of the kind cluster configuration by node role, the
+// configuration is merged with the raw configuration, so its nodes can override the images
+// of the spec. The image of the first node of each role is reported.
+func getNodeImages(config *v1alpha4.Cluster) map[string]string {
+	images := map[string]string{}
+
+	for _, node := range config.Nodes {
+		if _, ok := images[string(node.Role)]; !ok && node.Image != "" {
+			images[string(node.Role)] = node.Image
+		}
+	}
+
+	return images
+}
+
+// Get the effective networking options of the cluster configuration, the options
+// that are not specified are filled with the defaults of the kind tool
+func getEffectiveNetworking(config *v1alpha4.Cluster) *infrastructurev1alpha1.KINDClusterNetworking {
+	defaulted := config.DeepCopy()
+	v1alpha4.SetDefaultsCluster(defaulted)
+
+	return &infrastructurev1alpha1.KINDClusterNetworking{
+		IPFamily:          string(defaulted.Networking.IPFamily),
+		APIServerAddress:  defaulted.Networking.APIServerAddress,
+		APIServerPort:     defaulted.Networking.APIServerPort,
+		PodSubnet:         defaulted.Networking.PodSubnet,
+		ServiceSubnet:     defaulted.Networking.ServiceSubnet,
+		DisableDefaultCNI: defaulted.Networking.DisableDefaultCNI,
+		KubeProxyMode:     string(defaulted.Networking.KubeProxyMode),
+	}
+}
+
+// Get the port of the API server from the server URL of the kubeconfig
+func getAPIServerPort(kubeconfig string) (int32, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+
+	if err != nil {
+		return 0, err
+	}
+
+	context, ok := config.Contexts[config.CurrentContext]
+
+	if !ok {
+		return 0, fmt.Errorf("current context %q cannot be found in kubeconfig", config.CurrentContext)
+	}
+
+	cluster, ok := config.Clusters[context.Cluster]
+
+	if !ok {
+		return 0, fmt.Errorf("cluster %q cannot be found in kubeconfig", context.Cluster)
+	}
+
+	serverURL, err := url.Parse(cluster.Server)
+
+	if err != nil {
+		return 0, err
+	}
+
+	port, err := strconv.ParseInt(serverURL.Port(), 10, 32)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid API server port in %q: %w", cluster.Server, err)
+	}
+
+	return int32(port), nil
+}
+
+// Build a kind node configuration from the node template of a role
+func buildKindNode(role v1alpha4.NodeRole, image string, template infrastructurev1alpha1.KINDNodeTemplate) v1alpha4.Node {
+	node := v1alpha4.Node{
+		Role:  role,
+		Image: image,
+	}
+
+	if len(template.Labels) > 0 {
+		node.Labels = make(map[string]string, len(template.Labels))
+
+		for key, value := range template.Labels {
+			node.Labels[key] = value
+		}
+	}
+
+	node.KubeadmConfigPatches = append(node.KubeadmConfigPatches, template.KubeadmConfigPatches...)
+
+	for _, mapping := range template.ExtraPortMappings {
+		node.ExtraPortMappings = append(node.ExtraPortMappings, v1alpha4.PortMapping{
+			ContainerPort: mapping.ContainerPort,
+			HostPort:      mapping.HostPort,
+			ListenAddress: mapping.ListenAddress,
+			Protocol:      v1alpha4.PortMappingProtocol(mapping.Protocol),
+		})
+	}
+
+	if len(template.ExtraMounts) > 0 {
+		node.ExtraMounts = getKindMounts(template.ExtraMounts)
+	}
+
+	return node
+}
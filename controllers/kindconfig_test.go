@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+func Test_BuildKindConfig(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		topology     *infrastructurev1alpha1.KINDClusterTopology
+		controlPlane int
+		workers      int
+	}{
+		{"default", nil, 1, 0},
+		{"workers", &infrastructurev1alpha1.KINDClusterTopology{
+			ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1},
+			Workers:      infrastructurev1alpha1.WorkerTopology{Replicas: 2},
+		}, 1, 2},
+		{"ha", &infrastructurev1alpha1.KINDClusterTopology{
+			ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 3},
+			Workers:      infrastructurev1alpha1.WorkerTopology{Replicas: 3},
+		}, 3, 3},
+		{"undefaulted", &infrastructurev1alpha1.KINDClusterTopology{
+			Workers: infrastructurev1alpha1.WorkerTopology{Replicas: 1},
+		}, 1, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := &infrastructurev1alpha1.KINDCluster{
+				Spec: infrastructurev1alpha1.KINDClusterSpec{
+					ClusterName:       tc.name,
+					KubernetesVersion: "1.21",
+					Topology:          tc.topology,
+				},
+			}
+
+			config := buildKindConfig(kindcluster)
+
+			controlPlane, workers := 0, 0
+
+			for _, node := range config.Nodes {
+				if node.Image != defaults.Image {
+					t.Errorf("buildKindConfig() image = %v, want %v", node.Image, defaults.Image)
+				}
+
+				switch node.Role {
+				case v1alpha4.ControlPlaneRole:
+					controlPlane++
+				case v1alpha4.WorkerRole:
+					workers++
+				}
+			}
+
+			if controlPlane != tc.controlPlane || workers != tc.workers {
+				t.Errorf("buildKindConfig() = %d control-plane, %d worker nodes, want %d, %d",
+					controlPlane, workers, tc.controlPlane, tc.workers)
+			}
+		})
+	}
+}
+
+func Test_BuildKindNodeLabels(t *testing.T) {
+	template := infrastructurev1alpha1.KINDNodeTemplate{
+		Labels: map[string]string{"tier": "frontend"},
+	}
+
+	node := buildKindNode(v1alpha4.WorkerRole, "", template)
+
+	if node.Labels["tier"] != "frontend" {
+		t.Errorf("buildKindNode() labels = %v, want %v", node.Labels, template.Labels)
+	}
+
+	// The node labels must not share the map of the template
+	node.Labels["tier"] = "backend"
+
+	if template.Labels["tier"] != "frontend" {
+		t.Errorf("buildKindNode() shares the labels map of the template")
+	}
+}
+
+func Test_BuildKindConfigPatches(t *testing.T) {
+	clusterPatch := "kind: ClusterConfiguration\napiServer:\n  extraArgs:\n    v: \"4\"\n"
+	workerPatch := "kind: JoinConfiguration\nnodeRegistration:\n  kubeletExtraArgs:\n    max-pods: \"50\"\n"
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			KubernetesVersion:    "1.21",
+			FeatureGates:         map[string]bool{"EphemeralContainers": true},
+			RuntimeConfig:        map[string]string{"api/alpha": "true"},
+			KubeadmConfigPatches: []string{clusterPatch},
+			Topology: &infrastructurev1alpha1.KINDClusterTopology{
+				ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1},
+				Workers: infrastructurev1alpha1.WorkerTopology{Replicas: 1, KINDNodeTemplate: infrastructurev1alpha1.KINDNodeTemplate{
+					KubeadmConfigPatches: []string{workerPatch},
+				}},
+			},
+		},
+	}
+
+	config := buildKindConfig(kindcluster)
+
+	if !reflect.DeepEqual(config.FeatureGates, kindcluster.Spec.FeatureGates) ||
+		!reflect.DeepEqual(config.RuntimeConfig, kindcluster.Spec.RuntimeConfig) ||
+		!reflect.DeepEqual(config.KubeadmConfigPatches, []string{clusterPatch}) {
+		t.Errorf("buildKindConfig() = %v, %v, %v, want the options of the spec",
+			config.FeatureGates, config.RuntimeConfig, config.KubeadmConfigPatches)
+	}
+
+	for _, node := range config.Nodes {
+		var want []string
+
+		if node.Role == v1alpha4.WorkerRole {
+			want = []string{workerPatch}
+		}
+
+		if !reflect.DeepEqual(node.KubeadmConfigPatches, want) {
+			t.Errorf("buildKindConfig() %s patches = %v, want %v", node.Role, node.KubeadmConfigPatches, want)
+		}
+	}
+}
+
+func Test_GetNodeImage(t *testing.T) {
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			KubernetesVersion: "1.21",
+			NodeImage:         "registry.local/node:cluster",
+			Topology: &infrastructurev1alpha1.KINDClusterTopology{
+				Workers: infrastructurev1alpha1.WorkerTopology{
+					Replicas:         1,
+					KINDNodeTemplate: infrastructurev1alpha1.KINDNodeTemplate{NodeImage: "registry.local/node:worker"},
+				},
+			},
+		},
+	}
+
+	want := map[string]string{
+		string(v1alpha4.ControlPlaneRole): "registry.local/node:cluster",
+		string(v1alpha4.WorkerRole):       "registry.local/node:worker",
+	}
+
+	if got := getNodeImages(buildKindConfig(kindcluster)); !reflect.DeepEqual(got, want) {
+		t.Errorf("getNodeImages() = %v, want %v", got, want)
+	}
+
+	// The images of the nodes of the raw configuration take precedence
+	raw, err := parseKindConfig("kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n" +
+		"- role: control-plane\n  image: registry.local/node:raw\n- role: worker\n")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kindcluster.Spec.Topology = nil
+
+	config, err := mergeKindConfig(raw, kindcluster)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = map[string]string{
+		string(v1alpha4.ControlPlaneRole): "registry.local/node:raw",
+		string(v1alpha4.WorkerRole):       "registry.local/node:cluster",
+	}
+
+	if got := getNodeImages(config); !reflect.DeepEqual(got, want) {
+		t.Errorf("getNodeImages() = %v, want %v", got, want)
+	}
+
+	// The built-in image of the version is used if no image is specified
+	kindcluster.Spec.NodeImage = ""
+
+	if got := getNodeImage(kindcluster, v1alpha4.ControlPlaneRole); got != defaults.Image {
+		t.Errorf("getNodeImage() = %v, want %v", got, defaults.Image)
+	}
+}
+
+func Test_GetEffectiveNetworking(t *testing.T) {
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName: "test",
+			Networking: &infrastructurev1alpha1.KINDClusterNetworking{
+				PodSubnet:         "192.168.0.0/16",
+				DisableDefaultCNI: true,
+				KubeProxyMode:     "ipvs",
+			},
+		},
+	}
+
+	networking := getEffectiveNetworking(buildKindConfig(kindcluster))
+
+	want := &infrastructurev1alpha1.KINDClusterNetworking{
+		IPFamily:          "ipv4",
+		APIServerAddress:  "127.0.0.1",
+		PodSubnet:         "192.168.0.0/16",
+		ServiceSubnet:     "10.96.0.0/16",
+		DisableDefaultCNI: true,
+		KubeProxyMode:     "ipvs",
+	}
+
+	if *networking != *want {
+		t.Errorf("getEffectiveNetworking() = %+v, want %+v", *networking, *want)
+	}
+}
+
+func Test_GetAPIServerPort(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		server  string
+		port    int32
+		wantErr bool
+	}{
+		{"port", "https://127.0.0.1:40123", 40123, false},
+		{"no-port", "https://127.0.0.1", 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: %s
+  name: kind-test
+contexts:
+- context:
+    cluster: kind-test
+    user: kind-test
+  name: kind-test
+current-context: kind-test
+`, tc.server)
+
+			port, err := getAPIServerPort(kubeconfig)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("getAPIServerPort() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if port != tc.port {
+				t.Errorf("getAPIServerPort() = %v, want %v", port, tc.port)
+			}
+		})
+	}
+}
+
+func Test_ParseKindConfig(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"valid", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nfeatureGates:\n  EphemeralContainers: true\n", false},
+		{"unknown field", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nfeatureGate:\n  EphemeralContainers: true\n", true},
+		{"wrong api version", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha3\n", true},
+		{"not yaml", "kind: [Cluster", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseKindConfig(tc.raw); (err != nil) != tc.wantErr {
+				t.Errorf("parseKindConfig() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_MergeKindConfig(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		raw     string
+		spec    infrastructurev1alpha1.KINDClusterSpec
+		nodes   int
+		wantErr bool
+	}{
+		{"feature gates", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nfeatureGates:\n  EphemeralContainers: true\n",
+			infrastructurev1alpha1.KINDClusterSpec{}, 1, false},
+		{"nodes of the raw configuration", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: control-plane\n- role: worker\n",
+			infrastructurev1alpha1.KINDClusterSpec{}, 2, false},
+		{"nodes and topology", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: control-plane\n",
+			infrastructurev1alpha1.KINDClusterSpec{Topology: &infrastructurev1alpha1.KINDClusterTopology{
+				ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1},
+			}}, 0, true},
+		{"same pod subnet", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnetworking:\n  podSubnet: 10.244.0.0/16\n",
+			infrastructurev1alpha1.KINDClusterSpec{Networking: &infrastructurev1alpha1.KINDClusterNetworking{
+				PodSubnet: "10.244.0.0/16",
+			}}, 1, false},
+		{"different pod subnet", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnetworking:\n  podSubnet: 10.244.0.0/16\n",
+			infrastructurev1alpha1.KINDClusterSpec{Networking: &infrastructurev1alpha1.KINDClusterNetworking{
+				PodSubnet: "10.10.0.0/16",
+			}}, 0, true},
+		{"same feature gate", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nfeatureGates:\n  EphemeralContainers: true\n",
+			infrastructurev1alpha1.KINDClusterSpec{FeatureGates: map[string]bool{"EphemeralContainers": true}}, 1, false},
+		{"different feature gate", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nfeatureGates:\n  EphemeralContainers: true\n",
+			infrastructurev1alpha1.KINDClusterSpec{FeatureGates: map[string]bool{"EphemeralContainers": false}}, 0, true},
+		{"different name", "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nname: other\n",
+			infrastructurev1alpha1.KINDClusterSpec{}, 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := &infrastructurev1alpha1.KINDCluster{Spec: tc.spec}
+			kindcluster.Spec.ClusterName = "test"
+			kindcluster.Spec.KubernetesVersion = "1.21"
+
+			raw, err := parseKindConfig(tc.raw)
+
+			if err != nil {
+				t.Fatalf("parseKindConfig() error = %v", err)
+			}
+
+			config, err := mergeKindConfig(raw, kindcluster)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("mergeKindConfig() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if config.Name != "test" || len(config.Nodes) != tc.nodes {
+				t.Errorf("mergeKindConfig() name = %v, %d nodes, want test, %d nodes", config.Name, len(config.Nodes), tc.nodes)
+			}
+
+			for _, node := range config.Nodes {
+				if node.Image != defaults.Image {
+					t.Errorf("mergeKindConfig() image = %v, want %v", node.Image, defaults.Image)
+				}
+			}
+
+			if !reflect.DeepEqual(config.FeatureGates, raw.FeatureGates) {
+				t.Errorf("mergeKindConfig() featureGates = %v, want %v", config.FeatureGates, raw.FeatureGates)
+			}
+		})
+	}
+}
@@ -0,0 +1,539 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+const (
+	machineFinalizerName = "kindmachines.infrastructure.cluster-k8s.io/machine-finalizer"
+
+	// Keys for logs
+	nodeNameKey = "nodeName"
+
+	// Address types of the nodes, as defined by Cluster API
+	machineAddressHostname   = "Hostname"
+	machineAddressInternalIP = "InternalIP"
+
+	// The maximum length of the node names, they are the hostnames of the node containers
+	// and the names of the Kubernetes nodes
+	maxNodeNameLength = 63
+
+	// The length of the hash suffix of the shortened node names
+	nodeNameHashLength = 8
+)
+
+// KINDMachineReconciler reconciles a KINDMachine object
+type KINDMachineReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Backend creates and deletes the nodes of the workload clusters
+	Backend backend.ClusterBackend
+
+	// MaxConcurrentReconciles is the maximum number of KINDMachines that are reconciled in parallel
+	MaxConcurrentReconciles int
+
+	// Tracks the nodes that are being created in the background
+	operations operationTracker
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindmachines,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindmachines/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindmachines/finalizers,verbs=update
+//+kubebuilder:rbac:groups=infrastructure.cluster-k8s.io,resources=kindmachinetemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch
+
+// Reconcile creates the node of a KINDMachine in its KINDCluster, and deletes it
+// when the KINDMachine is deleted
+func (r *KINDMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues(infrastructurev1alpha1.KindOfKindMachine, req.NamespacedName)
+	ctx = context.Background()
+
+	// Reconciliation starts
+	log.Info("Reconciling")
+
+	// Try to read the KINDMachine instance
+	var kindmachine infrastructurev1alpha1.KINDMachine
+
+	if err := r.Client.Get(ctx, req.NamespacedName, &kindmachine); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "unable to fetch KINDMachine instance")
+
+			return ctrl.Result{}, err
+		}
+
+		log.Info("KINDMachine resources cannot be found")
+
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch the Cluster API Machine that owns the KINDMachine and its Cluster, the
+	// KINDMachine can also be used without Cluster API, so they are optional
+	ownerMachine, err := getOwnerMachine(ctx, r.Client, &kindmachine)
+
+	if err != nil {
+		log.Error(err, "unable to fetch owner Machine")
+
+		return ctrl.Result{}, err
+	}
+
+	var ownerCluster *unstructured.Unstructured
+
+	if ownerMachine != nil {
+		if ownerCluster, err = getMachineCluster(ctx, r.Client, ownerMachine); err != nil {
+			log.Error(err, "unable to fetch Cluster of owner Machine")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Do not reconcile if the Cluster or the KINDMachine is paused
+	if isPaused(ownerCluster, &kindmachine) {
+		log.Info("Reconciliation is paused")
+
+		return ctrl.Result{}, nil
+	}
+
+	// The KINDCluster of a KINDMachine that is created by Cluster API is the
+	// infrastructure reference of its Cluster, it is stored in the spec so that
+	// the KINDMachines of a KINDCluster can be found
+	if kindmachine.Spec.KINDClusterName == "" {
+		if ownerCluster == nil {
+			log.Info("KINDCluster of KINDMachine is not known yet, waiting for the owner Machine")
+
+			return ctrl.Result{}, nil
+		}
+
+		requests := clusterToKINDCluster(ownerCluster)
+
+		if len(requests) == 0 {
+			log.Info("Cluster of owner Machine does not reference a KINDCluster")
+
+			return ctrl.Result{}, nil
+		}
+
+		kindmachine.Spec.KINDClusterName = requests[0].Name
+
+		if err := r.Update(ctx, &kindmachine); err != nil {
+			log.Error(err, "unable to set KINDCluster name")
+
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	// Try to read the KINDCluster of the KINDMachine, it may already be deleted
+	// while the KINDMachine is in deletion
+	var kindcluster infrastructurev1alpha1.KINDCluster
+
+	kindclusterFound := true
+
+	if err := r.Client.Get(ctx, types.NamespacedName{
+		Name:      kindmachine.Spec.KINDClusterName,
+		Namespace: kindmachine.Namespace,
+	}, &kindcluster); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "unable to fetch KINDCluster instance")
+
+			return ctrl.Result{}, err
+		}
+
+		kindclusterFound = false
+	}
+
+	clusterName := kindcluster.Spec.ClusterName
+	nodeName := getMachineNodeName(&kindmachine, clusterName)
+
+	// Check DeletionTimestamp to decide if object is in deletion
+	if kindmachine.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !containsString(machineFinalizerName, kindmachine.GetFinalizers()) {
+			controllerutil.AddFinalizer(&kindmachine, machineFinalizerName)
+
+			if err := r.Update(ctx, &kindmachine); err != nil {
+				log.Error(err, "unable to add finalizer")
+
+				return ctrl.Result{}, err
+			}
+
+			log.Info("Finalizer successfully added")
+
+			return ctrl.Result{}, nil
+		}
+	} else {
+		if containsString(machineFinalizerName, kindmachine.GetFinalizers()) {
+			// Wait for the background creation to finish before deleting the node
+			if op, ok := r.operations.get(nodeName); ok {
+				if !op.Done {
+					log.Info("Specified node is being created, deletion is waiting...", nodeNameKey, nodeName)
+
+					return ctrl.Result{RequeueAfter: operationPollInterval}, nil
+				}
+
+				r.operations.remove(nodeName)
+			}
+
+			// The nodes are deleted with their cluster, so there is nothing to
+			// delete if the KINDCluster is already deleted
+			if kindclusterFound {
+				log.Info("Node is deleting...", nodeNameKey, nodeName)
+
+				if err := r.Backend.DeleteNode(clusterName, nodeName); err != nil {
+					log.Error(err, "unable to delete node")
+
+					return ctrl.Result{}, err
+				}
+
+				log.Info("Node successfully deleted", nodeNameKey, nodeName)
+			}
+
+			controllerutil.RemoveFinalizer(&kindmachine, machineFinalizerName)
+
+			if err := r.Client.Update(ctx, &kindmachine); err != nil {
+				log.Error(err, "unable to update KINDMachine")
+
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	// The node can only join a cluster that is provisioned, the KINDMachine is
+	// reconciled again when the KINDCluster changes
+	if !kindclusterFound || kindcluster.Status.Ready == nil || !*kindcluster.Status.Ready {
+		log.Info("KINDCluster is not ready, waiting...", clusterNameKey, kindmachine.Spec.KINDClusterName)
+
+		setMachineConditions(&kindmachine, metav1.ConditionFalse, reasonWaitingForCluster,
+			fmt.Sprintf("KINDCluster %s is not ready", kindmachine.Spec.KINDClusterName))
+
+		kindmachine.Status.ObservedGeneration = kindmachine.Generation
+
+		if err := r.Client.Status().Update(ctx, &kindmachine); err != nil {
+			log.Error(err, "unable to update KINDMachine status")
+
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	var creationError error
+
+	provisioning := false
+	created := false
+
+	nodes, err := r.Backend.ListNodes(clusterName)
+
+	if err != nil {
+		log.Error(err, "unable to list nodes of cluster")
+
+		return ctrl.Result{}, err
+	}
+
+	node, nodeFound := findNode(nodes, nodeName)
+
+	if op, ok := r.operations.get(nodeName); ok {
+		provisioning, creationError = r.recordNodeCreation(&kindmachine, op, log)
+		created = !provisioning && creationError == nil
+	} else if nodeFound {
+		log.Info("Specified node exists", nodeNameKey, nodeName)
+
+		kindmachine.Status.Ready = true
+		kindmachine.Status.FailureReason = ""
+		kindmachine.Status.FailureMessage = ""
+		kindmachine.Status.NodeName = node.Name
+		kindmachine.Status.Addresses = getMachineAddresses(node)
+
+		setMachineConditions(&kindmachine, metav1.ConditionTrue, reasonProvisioned, "Node joined the cluster")
+
+		// Set the provider ID when the node is provisioned, as required by
+		// the Cluster API infrastructure machine contract
+		if kindmachine.Spec.ProviderID == nil || *kindmachine.Spec.ProviderID != node.ProviderID {
+			// Updating the object overwrites the status in memory, so it is restored
+			status := kindmachine.Status.DeepCopy()
+
+			providerID := node.ProviderID
+			kindmachine.Spec.ProviderID = &providerID
+
+			if err := r.Update(ctx, &kindmachine); err != nil {
+				log.Error(err, "unable to set provider ID")
+
+				return ctrl.Result{}, err
+			}
+
+			kindmachine.Status = *status
+		}
+	} else if kindmachine.Status.FailureReason != "" {
+		// A node that failed to be created is not created again, the KINDMachine
+		// should be replaced, as Cluster API does for the failed machines
+		log.Info("Specified node failed to be created, it is not created again", nodeNameKey, nodeName)
+
+		return ctrl.Result{}, nil
+	} else {
+		log.Info("Specified node does not exist, will be created...", nodeNameKey, nodeName)
+
+		options := backend.NodeOptions{
+			Name:        nodeName,
+			Role:        kindmachine.Spec.Role,
+			Image:       kindmachine.Spec.Image,
+			Labels:      kindmachine.Spec.Labels,
+			ExtraMounts: getKindMounts(kindmachine.Spec.ExtraMounts),
+			Machine:     kindmachine.Name,
+		}
+
+		if options.Role == "" {
+			options.Role = string(v1alpha4.WorkerRole)
+		}
+
+		if options.Image == "" {
+			options.Image = getNodeImage(&kindcluster, v1alpha4.NodeRole(options.Role))
+		}
+
+		op := r.operations.start(nodeName, operationTypeCreate, func(setStep func(string)) error {
+			setStep("Joining node")
+
+			_, err := r.Backend.CreateNode(clusterName, options)
+
+			return err
+		})
+
+		provisioning = true
+
+		kindmachine.Status.Ready = false
+		kindmachine.Status.NodeName = nodeName
+		kindmachine.Status.Operation = getOperationStatus(op)
+
+		setMachineConditions(&kindmachine, metav1.ConditionFalse, reasonProvisioning, "Node is being created")
+	}
+
+	kindmachine.Status.ObservedGeneration = kindmachine.Generation
+
+	if err := r.Client.Status().Update(ctx, &kindmachine); err != nil {
+		log.Error(err, "unable to update KINDMachine status")
+
+		return ctrl.Result{}, err
+	}
+
+	log.Info("KINDMachine status was updated", nodeNameKey, nodeName)
+
+	// If the node is being created, poll the progress of the creation later
+	if provisioning {
+		return ctrl.Result{RequeueAfter: operationPollInterval}, nil
+	}
+
+	// If an error occured while the creation of node, return the error after the
+	// status subresource was updated
+	if creationError != nil {
+		return ctrl.Result{}, creationError
+	}
+
+	// If the node was created, requeue to report its addresses and provider ID
+	if created {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Reconciliation finishes
+	log.Info("Reconciled")
+
+	return ctrl.Result{}, nil
+}
+
+// Record the progress or the result of the creation operation of the node in the status
+// It returns true if the creation is still in progress
+func (r *KINDMachineReconciler) recordNodeCreation(kindmachine *infrastructurev1alpha1.KINDMachine, op operation,
+	log logr.Logger) (bool, error) {
+	nodeName := kindmachine.Status.NodeName
+
+	if !op.Done {
+		log.Info("Specified node is being created...", nodeNameKey, nodeName, "step", op.Step)
+
+		kindmachine.Status.Operation = getOperationStatus(op)
+
+		return true, nil
+	}
+
+	// The result of the operation is recorded only once
+	r.operations.remove(nodeName)
+	kindmachine.Status.Operation = nil
+
+	if creationError := op.Err; creationError != nil {
+		log.Error(creationError, "unable to create node")
+
+		kindmachine.Status.FailureMessage = fmt.Sprintf("Node cannot be created: %s", creationError)
+		kindmachine.Status.FailureReason = failureReasonCreateError
+		kindmachine.Status.Ready = false
+
+		setMachineConditions(kindmachine, metav1.ConditionFalse, failureReasonCreateError, kindmachine.Status.FailureMessage)
+
+		return false, creationError
+	}
+
+	log.Info("Specified node was successfully created", nodeNameKey, nodeName)
+
+	return false, nil
+}
+
+// Set the NodeProvisioned and Ready conditions of the KINDMachine instance, the node is
+// ready as soon as it joined the cluster
+func setMachineConditions(kindmachine *infrastructurev1alpha1.KINDMachine, status metav1.ConditionStatus,
+	reason, message string) {
+	conditions := machineConditions(kindmachine)
+
+	conditions.set(infrastructurev1alpha1.NodeProvisionedCondition, status, reason, message)
+	conditions.set(infrastructurev1alpha1.ReadyCondition, status, reason, message)
+}
+
+// Get the name of the node container of the KINDMachine, it is prefixed with the
+// cluster name as the names of the other node containers of the cluster
+// The names that are too long for a hostname are shortened, and a hash of the cluster name
+// and the KINDMachine name is appended so that the shortened names do not collide.
+func getMachineNodeName(kindmachine *infrastructurev1alpha1.KINDMachine, clusterName string) string {
+	if kindmachine.Status.NodeName != "" {
+		return kindmachine.Status.NodeName
+	}
+
+	nodeName := fmt.Sprintf("%s-%s", clusterName, kindmachine.Name)
+
+	if len(nodeName) <= maxNodeNameLength {
+		return nodeName
+	}
+
+	sum := sha256.Sum256([]byte(clusterName + "/" + kindmachine.Name))
+	hash := hex.EncodeToString(sum[:])[:nodeNameHashLength]
+
+	prefix := strings.TrimRight(nodeName[:maxNodeNameLength-nodeNameHashLength-1], "-.")
+
+	return prefix + "-" + hash
+}
+
+// Find the node with the specified name
+func findNode(nodes []backend.Node, name string) (backend.Node, bool) {
+	for _, node := range nodes {
+		if node.Name == name {
+			return node, true
+		}
+	}
+
+	return backend.Node{}, false
+}
+
+// Get the addresses of the node in the format of Cluster API
+func getMachineAddresses(node backend.Node) []infrastructurev1alpha1.MachineAddress {
+	addresses := []infrastructurev1alpha1.MachineAddress{{
+		Type:    machineAddressHostname,
+		Address: node.Name,
+	}}
+
+	for _, ip := range []string{node.IPv4, node.IPv6} {
+		if ip != "" {
+			addresses = append(addresses, infrastructurev1alpha1.MachineAddress{
+				Type:    machineAddressInternalIP,
+				Address: ip,
+			})
+		}
+	}
+
+	return addresses
+}
+
+// Convert the mounts in the spec of KINDMachine instance to the kind mounts
+func getKindMounts(mounts []infrastructurev1alpha1.Mount) []v1alpha4.Mount {
+	kindMounts := make([]v1alpha4.Mount, 0, len(mounts))
+
+	for _, mount := range mounts {
+		kindMounts = append(kindMounts, v1alpha4.Mount{
+			HostPath:      mount.HostPath,
+			ContainerPath: mount.ContainerPath,
+			Readonly:      mount.ReadOnly,
+		})
+	}
+
+	return kindMounts
+}
+
+// Map a KINDCluster to its KINDMachines, so that the nodes are created when the
+// KINDCluster is ready
+func (r *KINDMachineReconciler) kindClusterToKINDMachines(obj client.Object) []reconcile.Request {
+	var kindmachines infrastructurev1alpha1.KINDMachineList
+
+	if err := r.Client.List(context.Background(), &kindmachines, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list KINDMachines")
+
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, kindmachine := range kindmachines.Items {
+		if kindmachine.Spec.KINDClusterName == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      kindmachine.Name,
+				Namespace: kindmachine.Namespace,
+			}})
+		}
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KINDMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&infrastructurev1alpha1.KINDMachine{}).
+		Watches(&source.Kind{Type: &infrastructurev1alpha1.KINDCluster{}},
+			handler.EnqueueRequestsFromMapFunc(r.kindClusterToKINDMachines)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles})
+
+	// Watch the Cluster API Machines to react to the changes of the owners,
+	// only if Cluster API is installed in the management cluster
+	if _, err := mgr.GetRESTMapper().RESTMapping(capiMachineGVK.GroupKind(), capiMachineGVK.Version); err == nil {
+		capiMachine := &unstructured.Unstructured{}
+		capiMachine.SetGroupVersionKind(capiMachineGVK)
+
+		builder = builder.Watches(&source.Kind{Type: capiMachine},
+			handler.EnqueueRequestsFromMapFunc(machineToKINDMachine))
+	} else {
+		r.Log.Info("Cluster API Machine kind cannot be found, owner Machines are not watched")
+	}
+
+	return builder.Complete(r)
+}
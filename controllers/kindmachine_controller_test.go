@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newKINDMachineTestEnv(t *testing.T, ready bool, objs ...runtime.Object) (*KINDMachineReconciler, client.Client, *backend.FakeBackend) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-machine",
+			Namespace: defaultNamespace,
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       "test-machine",
+			KubernetesVersion: "1.20",
+		},
+		Status: infrastructurev1alpha1.KINDClusterStatus{
+			Ready: &ready,
+		},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, append(objs, kindcluster)...)
+	b := backend.NewFakeBackend()
+
+	if err := b.Create(kindcluster.Spec.ClusterName, nil, backend.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &KINDMachineReconciler{
+		Client:  c,
+		Scheme:  testScheme,
+		Log:     ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindMachine),
+		Backend: b,
+	}
+
+	return r, c, b
+}
+
+func Test_ReconcileKINDMachine(t *testing.T) {
+	kindmachine := &infrastructurev1alpha1.KINDMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "worker-a",
+			Namespace: defaultNamespace,
+		},
+		Spec: infrastructurev1alpha1.KINDMachineSpec{
+			KINDClusterName: "test-machine",
+			Role:            "worker",
+		},
+	}
+
+	r, c, b := newKINDMachineTestEnv(t, true, kindmachine)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindmachine.Name, Namespace: defaultNamespace}}
+
+	// The first reconciliation adds the finalizer and the second one starts the creation
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	waitForOperation(t, &r.operations, "test-machine-worker-a")
+
+	// The third reconciliation records the creation and the fourth one observes the node
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDMachine{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reconciled.Status.Ready || reconciled.Status.NodeName != "test-machine-worker-a" {
+		t.Errorf("Reconcile() ready = %v, nodeName = %v, want a ready node test-machine-worker-a",
+			reconciled.Status.Ready, reconciled.Status.NodeName)
+	}
+
+	wantProviderID := "kind://fake/test-machine/test-machine-worker-a"
+
+	if reconciled.Spec.ProviderID == nil || *reconciled.Spec.ProviderID != wantProviderID {
+		t.Errorf("Reconcile() providerID = %v, want %s", reconciled.Spec.ProviderID, wantProviderID)
+	}
+
+	if len(reconciled.Status.Addresses) != 2 {
+		t.Errorf("Reconcile() addresses = %v, want a hostname and an internal IP", reconciled.Status.Addresses)
+	}
+
+	nodes, _ := b.ListNodes("test-machine")
+
+	if node, ok := findNode(nodes, "test-machine-worker-a"); !ok || node.Machine != kindmachine.Name ||
+		node.KubernetesVersion != "v1.20.7" {
+		t.Errorf("Reconcile() node = %+v, want a v1.20.7 node of machine %s", node, kindmachine.Name)
+	}
+
+	// Deleting the instance deletes the node and removes the finalizer
+	if err := c.Delete(context.Background(), reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	nodes, _ = b.ListNodes("test-machine")
+
+	if _, ok := findNode(nodes, "test-machine-worker-a"); ok {
+		t.Errorf("Reconcile() nodes = %v, want the node deleted", nodes)
+	}
+
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); !k8serrors.IsNotFound(err) {
+		t.Errorf("Reconcile() KINDMachine error = %v, want not found", err)
+	}
+}
+
+func Test_ReconcileKINDMachineWaitsForCluster(t *testing.T) {
+	kindmachine := &infrastructurev1alpha1.KINDMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "worker-a",
+			Namespace:  defaultNamespace,
+			Finalizers: []string{machineFinalizerName},
+		},
+		Spec: infrastructurev1alpha1.KINDMachineSpec{
+			KINDClusterName: "test-machine",
+		},
+	}
+
+	r, _, b := newKINDMachineTestEnv(t, false, kindmachine)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindmachine.Name, Namespace: defaultNamespace}}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if _, ok := r.operations.get("test-machine-worker-a"); ok {
+		t.Errorf("Reconcile() started the creation, want it to wait for the KINDCluster")
+	}
+
+	if nodes, _ := b.ListNodes("test-machine"); len(nodes) != 1 {
+		t.Errorf("Reconcile() nodes = %v, want only the control-plane node", nodes)
+	}
+}
+
+func Test_ReconcileKINDMachineOwnerMachine(t *testing.T) {
+	cluster := newCAPICluster("test-machine", false)
+
+	machine := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"clusterName": "test-machine",
+		},
+	}}
+	machine.SetGroupVersionKind(capiMachineGVK)
+	machine.SetName("machine-a")
+	machine.SetNamespace(defaultNamespace)
+
+	kindmachine := &infrastructurev1alpha1.KINDMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machine-a",
+			Namespace: defaultNamespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: capiMachineGVK.GroupVersion().String(),
+				Kind:       capiMachineGVK.Kind,
+				Name:       "machine-a",
+			}},
+		},
+	}
+
+	r, c, _ := newKINDMachineTestEnv(t, true, cluster, machine, kindmachine)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: kindmachine.Name, Namespace: defaultNamespace}}
+
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	reconciled := &infrastructurev1alpha1.KINDMachine{}
+	if err := c.Get(context.Background(), req.NamespacedName, reconciled); err != nil {
+		t.Fatal(err)
+	}
+
+	if reconciled.Spec.KINDClusterName != "test-machine" {
+		t.Errorf("Reconcile() kindClusterName = %q, want the infrastructure reference of the Cluster",
+			reconciled.Spec.KINDClusterName)
+	}
+}
+
+func Test_GetMachineNodeName(t *testing.T) {
+	longName := "machine-deployment-md-0-7d9c5b6f8c-" + strings.Repeat("x", 30)
+
+	var testCases = []struct {
+		name        string
+		clusterName string
+		machineName string
+		nodeName    string
+		want        string
+	}{
+		{"short name", "test", "worker", "", "test-worker"},
+		{"recorded name", "test", "worker", "test-node", "test-node"},
+		{"long name", "test", longName, "", "test-" + longName[:49] + "-" + nodeNameHash("test", longName)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindmachine := &infrastructurev1alpha1.KINDMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: tc.machineName},
+				Status:     infrastructurev1alpha1.KINDMachineStatus{NodeName: tc.nodeName},
+			}
+
+			if got := getMachineNodeName(kindmachine, tc.clusterName); got != tc.want || len(got) > maxNodeNameLength {
+				t.Errorf("getMachineNodeName() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// The shortened names of different KINDMachines do not collide
+	first := getMachineNodeName(&infrastructurev1alpha1.KINDMachine{ObjectMeta: metav1.ObjectMeta{Name: longName + "a"}}, "test")
+	second := getMachineNodeName(&infrastructurev1alpha1.KINDMachine{ObjectMeta: metav1.ObjectMeta{Name: longName + "b"}}, "test")
+
+	if first == second {
+		t.Errorf("getMachineNodeName() = %v for different KINDMachines, want different names", first)
+	}
+}
+
+func nodeNameHash(clusterName, machineName string) string {
+	sum := sha256.Sum256([]byte(clusterName + "/" + machineName))
+
+	return hex.EncodeToString(sum[:])[:nodeNameHashLength]
+}
+
+func Test_GetMachineAddresses(t *testing.T) {
+	node := backend.Node{Name: "test-worker", IPv4: "172.18.0.2", IPv6: "fc00:f853:ccd:e793::2"}
+
+	want := []infrastructurev1alpha1.MachineAddress{
+		{Type: "Hostname", Address: "test-worker"},
+		{Type: "InternalIP", Address: "172.18.0.2"},
+		{Type: "InternalIP", Address: "fc00:f853:ccd:e793::2"},
+	}
+
+	if got := getMachineAddresses(node); !reflect.DeepEqual(got, want) {
+		t.Errorf("getMachineAddresses() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// The kubeconfig secret follows the Cluster API convention, so that the Cluster API
+// tooling, for example clusterctl get kubeconfig, can find it
+const (
+	// Key of the kubeconfig in the secret
+	kubeconfigSecretKey = "value"
+
+	// Key of the kubeconfig in the legacy secret
+	legacyKubeconfigSecretKey = "config"
+
+	// Key of the kubeconfig that can be used from the network of the node containers,
+	// for example by the controllers that run in another kind cluster
+	internalKubeconfigSecretKey = "internal"
+
+	// Type of the secrets that Cluster API manages
+	clusterSecretType corev1.SecretType = "cluster.x-k8s.io/secret"
+
+	// Label of the objects that belong to a Cluster API Cluster
+	clusterNameLabel = "cluster.x-k8s.io/cluster-name"
+)
+
+// kubeconfigs are the variants of the kubeconfig of a cluster
+type kubeconfigs struct {
+	// External is the kubeconfig that can be used from the host of the node containers
+	External []byte
+
+	// Internal is the kubeconfig that can be used from the network of the node containers
+	Internal []byte
+}
+
+// Get the kubeconfigs of the cluster from the backend
+func getKubeconfigs(clusterBackend backend.ClusterBackend, clusterName string) (kubeconfigs, error) {
+	external, err := clusterBackend.KubeConfig(clusterName, false)
+
+	if err != nil {
+		return kubeconfigs{}, err
+	}
+
+	internal, err := clusterBackend.KubeConfig(clusterName, true)
+
+	if err != nil {
+		return kubeconfigs{}, err
+	}
+
+	return kubeconfigs{External: []byte(external), Internal: []byte(internal)}, nil
+}
+
+// Get the name of the Cluster API Cluster of the KINDCluster, it is the name of the
+// owner Cluster, or the name of the KINDCluster if it is not owned by a Cluster
+func getCAPIClusterName(kindcluster *infrastructurev1alpha1.KINDCluster, ownerCluster *unstructured.Unstructured) string {
+	if ownerCluster != nil {
+		return ownerCluster.GetName()
+	}
+
+	return kindcluster.Name
+}
+
+// Get the kubeconfig secret name from the Cluster API Cluster name
+func getKubeconfigSecretName(capiClusterName string) string {
+	return fmt.Sprintf("%s-%s", capiClusterName, "kubeconfig")
+}
+
+// Get the name of the kubeconfig secret that was written for the KINDCluster, the name of the
+// Cluster API Cluster changes if Cluster API sets the owner reference after the secret was written
+func getStoredKubeconfigSecretName(kindcluster *infrastructurev1alpha1.KINDCluster, capiClusterName string) string {
+	if name := kindcluster.Status.KubeconfigSecretName; name != "" {
+		return name
+	}
+
+	return getKubeconfigSecretName(capiClusterName)
+}
+
+// Get the hash of the kubeconfig, it changes when the certificates or the endpoint
+// of the cluster change, so the consumers of the secrets can detect the rotations
+func hashKubeconfig(kubeconfig []byte) string {
+	sum := sha256.Sum256(kubeconfig)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Update the data of the existing secret if it is different from the specified data
+// It returns true if the secret was updated
+func updateSecretData(c client.Client, secret *corev1.Secret, data map[string][]byte) (bool, error) {
+	if reflect.DeepEqual(secret.Data, data) {
+		return false, nil
+	}
+
+	secret.Data = data
+
+	if err := c.Update(context.Background(), secret); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Store the kubeconfig of the cluster in a secret in the Cluster API format, the
+// secret is owned by the KINDCluster so that it is garbage collected with it
+// The secret is updated if the kubeconfig changed.
+func storeKubeconfigInCAPISecret(c client.Client, scheme *runtime.Scheme, kindcluster *infrastructurev1alpha1.KINDCluster,
+	capiClusterName string, kubeconfigs kubeconfigs, log logr.Logger) error {
+	secretName := getKubeconfigSecretName(capiClusterName)
+
+	data := map[string][]byte{
+		kubeconfigSecretKey:         kubeconfigs.External,
+		internalKubeconfigSecretKey: kubeconfigs.Internal,
+	}
+
+	kubeconfigSecret := &corev1.Secret{}
+
+	if err := c.Get(context.Background(),
+		types.NamespacedName{
+			Name:      secretName,
+			Namespace: kindcluster.Namespace,
+		}, kubeconfigSecret); err == nil {
+		updated, err := updateSecretData(c, kubeconfigSecret, data)
+
+		if err != nil {
+			return err
+		}
+
+		if updated {
+			log.Info("Kubeconfig secret successfully updated", secretNameKey, secretName,
+				clusterNameKey, kindcluster.Spec.ClusterName)
+		}
+
+		return nil
+	} else if !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	kubeconfigSecret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: kindcluster.Namespace,
+			Labels: map[string]string{
+				clusterNameLabel: capiClusterName,
+			},
+		},
+		Type: clusterSecretType,
+		Data: data,
+	}
+
+	if err := controllerutil.SetControllerReference(kindcluster, kubeconfigSecret, scheme); err != nil {
+		return err
+	}
+
+	if err := c.Create(context.Background(), kubeconfigSecret); err != nil {
+		return err
+	}
+
+	log.Info("Kubeconfig secret successfully created", secretNameKey, secretName,
+		clusterNameKey, kindcluster.Spec.ClusterName)
+
+	return nil
+}
+
+// Delete the kubeconfig secret in the Cluster API format
+// It is also garbage collected with the KINDCluster, it is deleted explicitly
+// so that a recreated cluster gets a new secret
+func deleteKubeconfigSecret(c client.Client, log logr.Logger, secretName, namespace string) error {
+	if err := c.Delete(context.Background(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      secretName,
+		Namespace: namespace,
+	}}); err != nil && !k8serrors.IsNotFound(err) {
+		log.Error(err, "unable to delete kubeconfig secret")
+
+		return err
+	}
+
+	log.Info("Kubeconfig secret successfully deleted", secretNameKey, secretName)
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_GetCAPIClusterName(t *testing.T) {
+	kindcluster := &infrastructurev1alpha1.KINDCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	if got := getCAPIClusterName(kindcluster, nil); got != "test" {
+		t.Errorf("getCAPIClusterName() = %v, want test", got)
+	}
+
+	if got := getCAPIClusterName(kindcluster, newCAPICluster("owner", false)); got != "owner" {
+		t.Errorf("getCAPIClusterName() = %v, want owner", got)
+	}
+}
+
+func Test_StoreKubeconfigInCAPISecret(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: defaultNamespace,
+			UID:       "test-uid",
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{ClusterName: "test"},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+	log := ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster)
+
+	if err := storeKubeconfigInCAPISecret(c, testScheme, kindcluster, "test", kubeconfigs{
+		External: []byte("kubeconfigData"),
+		Internal: []byte("internalKubeconfigData"),
+	}, log); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "test-kubeconfig", Namespace: defaultNamespace}, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secret.Data["value"]) != "kubeconfigData" || string(secret.Data["internal"]) != "internalKubeconfigData" {
+		t.Errorf("storeKubeconfigInCAPISecret() data = %v, want the kubeconfigs in the value and internal keys", secret.Data)
+	}
+
+	if secret.Type != "cluster.x-k8s.io/secret" || secret.Labels["cluster.x-k8s.io/cluster-name"] != "test" {
+		t.Errorf("storeKubeconfigInCAPISecret() type = %v, labels = %v, want the Cluster API format",
+			secret.Type, secret.Labels)
+	}
+
+	if refs := secret.OwnerReferences; len(refs) != 1 || refs[0].Kind != infrastructurev1alpha1.KindOfKindCluster ||
+		refs[0].Name != "test" {
+		t.Errorf("storeKubeconfigInCAPISecret() ownerReferences = %v, want the KINDCluster", refs)
+	}
+
+	// The secret is updated when the kubeconfig changes, for example when the cluster is recreated
+	if err := storeKubeconfigInCAPISecret(c, testScheme, kindcluster, "test", kubeconfigs{
+		External: []byte("newKubeconfigData"),
+		Internal: []byte("internalKubeconfigData"),
+	}, log); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "test-kubeconfig", Namespace: defaultNamespace}, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secret.Data["value"]) != "newKubeconfigData" {
+		t.Errorf("storeKubeconfigInCAPISecret() data = %v, want the new kubeconfig", secret.Data)
+	}
+
+	if err := deleteKubeconfigSecret(c, log, getKubeconfigSecretName("test"), defaultNamespace); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting a missing secret is not an error
+	if err := deleteKubeconfigSecret(c, log, getKubeconfigSecretName("test"), defaultNamespace); err != nil {
+		t.Errorf("deleteKubeconfigSecret() error = %v", err)
+	}
+}
+
+func Test_StoreKubeconfigsOwnerChanged(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: defaultNamespace,
+			UID:       "test-uid",
+		},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{ClusterName: "test"},
+	}
+
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+	log := ctrl.Log.WithValues(infrastructurev1alpha1.KindOfKindCluster)
+
+	r := &KINDClusterReconciler{Client: c, Scheme: testScheme, Log: log}
+	data := kubeconfigs{External: []byte("kubeconfigData"), Internal: []byte("internalKubeconfigData")}
+
+	// The secret is written before Cluster API sets the owner reference
+	if err := r.storeKubeconfigs(kindcluster, "test", data, log); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.storeKubeconfigs(kindcluster, "owner", data, log); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := kindcluster.Status.KubeconfigSecretName; name != "owner-kubeconfig" {
+		t.Errorf("storeKubeconfigs() kubeconfigSecretName = %v, want owner-kubeconfig", name)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "test-kubeconfig", Namespace: defaultNamespace},
+		secret); !k8serrors.IsNotFound(err) {
+		t.Errorf("storeKubeconfigs() secret of the previous name error = %v, want not found", err)
+	}
+
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "owner-kubeconfig", Namespace: defaultNamespace},
+		secret); err != nil {
+		t.Errorf("storeKubeconfigs() secret error = %v", err)
+	}
+}
+
+func Test_HashKubeconfig(t *testing.T) {
+	hash := hashKubeconfig([]byte("kubeconfigData"))
+
+	if len(hash) != 64 {
+		t.Errorf("hashKubeconfig() = %v, want a hex encoded SHA-256 hash", hash)
+	}
+
+	if hash == hashKubeconfig([]byte("newKubeconfigData")) {
+		t.Errorf("hashKubeconfig() = %v for different kubeconfigs, want different hashes", hash)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// operation is a long running task on a cluster, it runs in the background
+// so that it does not block the reconcile worker
+type operation struct {
+	Type      string
+	StartTime metav1.Time
+	Step      string
+	Done      bool
+	Err       error
+}
+
+// operationTracker tracks the background operations by cluster name
+// The zero value is ready to use
+type operationTracker struct {
+	mu         sync.Mutex
+	operations map[string]*operation
+}
+
+// Start the function as a background operation of the cluster, the function reports
+// its progress by calling setStep. If an operation of the cluster is already tracked,
+// a new one is not started.
+func (t *operationTracker) start(clusterName, operationType string, fn func(setStep func(string)) error) operation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.operations == nil {
+		t.operations = map[string]*operation{}
+	}
+
+	if op, ok := t.operations[clusterName]; ok {
+		return *op
+	}
+
+	op := &operation{
+		Type:      operationType,
+		StartTime: metav1.Now(),
+	}
+
+	t.operations[clusterName] = op
+
+	setStep := func(step string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		op.Step = step
+	}
+
+	go func() {
+		err := fn(setStep)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		op.Done = true
+		op.Err = err
+	}()
+
+	return *op
+}
+
+// Get a copy of the tracked operation of the cluster
+func (t *operationTracker) get(clusterName string) (operation, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	op, ok := t.operations[clusterName]
+
+	if !ok {
+		return operation{}, false
+	}
+
+	return *op, true
+}
+
+// Stop tracking the operation of the cluster, it is called after the result
+// of a finished operation was recorded
+func (t *operationTracker) remove(clusterName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.operations, clusterName)
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_OperationTracker(t *testing.T) {
+	var tracker operationTracker
+
+	release := make(chan struct{})
+
+	op := tracker.start("test", operationTypeCreate, func(setStep func(string)) error {
+		setStep("first step")
+		<-release
+
+		return errors.New("failed")
+	})
+
+	if op.Type != operationTypeCreate || op.Done {
+		t.Errorf("start() = %+v, want a running %s operation", op, operationTypeCreate)
+	}
+
+	// Starting again does not start a second operation
+	second := tracker.start("test", operationTypeCreate, func(setStep func(string)) error {
+		t.Errorf("start() started a second operation")
+
+		return nil
+	})
+
+	if !second.StartTime.Equal(&op.StartTime) {
+		t.Errorf("start() = %+v, want the tracked operation %+v", second, op)
+	}
+
+	close(release)
+
+	for {
+		op, ok := tracker.get("test")
+
+		if !ok {
+			t.Fatal("get() operation not found")
+		}
+
+		if op.Done {
+			if op.Err == nil || op.Step != "first step" {
+				t.Errorf("get() = %+v, want a failed operation at the first step", op)
+			}
+
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	tracker.remove("test")
+
+	if _, ok := tracker.get("test"); ok {
+		t.Errorf("get() after remove() found the operation")
+	}
+}
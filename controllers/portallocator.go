@@ -0,0 +1,209 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+// errPortRangeExhausted is returned when all ports of the range are in use
+var errPortRangeExhausted = errors.New("host port range is exhausted")
+
+// PortAllocator allocates the host ports of the clusters from a range
+// The allocations are persisted in the status of the KINDClusters, the allocator only keeps
+// the ports that were allocated by this controller instance, so that the clusters that are
+// reconciled in parallel do not get the same port before their status is updated.
+type PortAllocator struct {
+	// ProbeHostPorts enables listening on a port before it is allocated, so that the ports
+	// that other processes of the host use are not allocated. The controller must run on
+	// the host of the container runtime then.
+	ProbeHostPorts bool
+
+	first, last int32
+
+	mu sync.Mutex
+	// The owners of the ports that were allocated, by port
+	reserved map[int32]string
+}
+
+// NewPortAllocator creates a port allocator for the range in the first-last format,
+// for example 40000-40999
+func NewPortAllocator(portRange string) (*PortAllocator, error) {
+	bounds := strings.Split(portRange, "-")
+
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("port range %q must be in the first-last format", portRange)
+	}
+
+	first, err := strconv.ParseInt(strings.TrimSpace(bounds[0]), 10, 32)
+
+	if err != nil {
+		return nil, fmt.Errorf("first port of range %q is not valid: %w", portRange, err)
+	}
+
+	last, err := strconv.ParseInt(strings.TrimSpace(bounds[1]), 10, 32)
+
+	if err != nil {
+		return nil, fmt.Errorf("last port of range %q is not valid: %w", portRange, err)
+	}
+
+	if first < 1 || last > 65535 || first > last {
+		return nil, fmt.Errorf("port range %q must be between 1 and 65535", portRange)
+	}
+
+	return &PortAllocator{
+		first:    int32(first),
+		last:     int32(last),
+		reserved: map[int32]string{},
+	}, nil
+}
+
+// Allocate the host ports that the kind cluster configuration of the KINDCluster instance
+// needs: the API server port if it is not specified, and the ingress ports that are not
+// specified
+// The ports that were allocated before are kept if they are still free, the ports of
+// the other KINDClusters are never allocated. It returns the allocated ports by purpose.
+func (a *PortAllocator) allocate(ctx context.Context, c client.Reader, kindcluster *infrastructurev1alpha1.KINDCluster,
+	config *v1alpha4.Cluster) (map[string]int32, error) {
+	var names []string
+
+	if config.Networking.APIServerPort == 0 {
+		names = append(names, portNameAPIServer)
+	}
+
+	if ingress := kindcluster.Spec.Ingress; ingress != nil {
+		if ingress.HTTPPort == 0 {
+			names = append(names, portNameHTTP)
+		}
+
+		if ingress.HTTPSPort == 0 {
+			names = append(names, portNameHTTPS)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	owner := client.ObjectKeyFromObject(kindcluster).String()
+
+	otherPorts, err := listOtherHostPorts(ctx, c, kindcluster)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	used := map[int32]bool{}
+
+	// The previous reservations of the cluster are replaced by the new allocations
+	for port, portOwner := range a.reserved {
+		if portOwner == owner {
+			delete(a.reserved, port)
+		} else {
+			used[port] = true
+		}
+	}
+
+	for _, ports := range otherPorts {
+		for _, port := range ports {
+			used[port.port] = true
+		}
+	}
+
+	ports := append(getHostPorts(withPortAllocations(config, kindcluster)), getRegistryHostPorts(kindcluster)...)
+
+	for _, port := range ports {
+		if port.port != kindcluster.Status.AllocatedPorts[portNameAPIServer] &&
+			port.port != kindcluster.Status.AllocatedPorts[portNameHTTP] &&
+			port.port != kindcluster.Status.AllocatedPorts[portNameHTTPS] {
+			used[port.port] = true
+		}
+	}
+
+	allocated := map[string]int32{}
+
+	for _, name := range names {
+		port, ok := kindcluster.Status.AllocatedPorts[name]
+
+		if !ok || used[port] {
+			if port, ok = a.findFreePort(used); !ok {
+				// The ports are allocated all together or not at all
+				for _, reserved := range allocated {
+					delete(a.reserved, reserved)
+				}
+
+				return nil, errPortRangeExhausted
+			}
+		}
+
+		used[port] = true
+		a.reserved[port] = owner
+		allocated[name] = port
+	}
+
+	return allocated, nil
+}
+
+// Find the first port of the range that is not used, and that is free on the host if the
+// ports are probed
+func (a *PortAllocator) findFreePort(used map[int32]bool) (int32, bool) {
+	for port := a.first; port <= a.last; port++ {
+		if !used[port] && (!a.ProbeHostPorts || isHostPortFree(port)) {
+			return port, true
+		}
+	}
+
+	return 0, false
+}
+
+// Check whether the port can be listened on all addresses of the host
+func isHostPortFree(port int32) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+
+	if err != nil {
+		return false
+	}
+
+	_ = listener.Close()
+
+	return true
+}
+
+// Release the ports that were allocated to the KINDCluster with the namespaced name
+func (a *PortAllocator) release(owner string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for port, portOwner := range a.reserved {
+		if portOwner == owner {
+			delete(a.reserved, port)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_NewPortAllocator(t *testing.T) {
+	var testCases = []struct {
+		portRange string
+		wantErr   bool
+	}{
+		{"40000-40999", false},
+		{"40000-40000", false},
+		{"40000", true},
+		{"40999-40000", true},
+		{"0-100", true},
+		{"60000-70000", true},
+		{"a-b", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.portRange, func(t *testing.T) {
+			if _, err := NewPortAllocator(tc.portRange); (err != nil) != tc.wantErr {
+				t.Errorf("NewPortAllocator() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_AllocatePorts(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	// The first port of the range is allocated to another cluster
+	other := newPortTestKINDCluster("other", 0)
+	other.Status.AllocatedPorts = map[string]int32{portNameAPIServer: 40000}
+
+	c := fake.NewFakeClientWithScheme(testScheme, other)
+
+	allocator, err := NewPortAllocator("40000-40003")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kindcluster := newPortTestKINDCluster("test", 0)
+	kindcluster.Spec.Ingress = &infrastructurev1alpha1.KINDClusterIngress{HTTPSPort: 8443}
+
+	// The registry of the cluster is published on a port of the range
+	kindcluster.Spec.Registry = &infrastructurev1alpha1.KINDClusterRegistry{HostPort: 40001}
+
+	got, err := allocator.allocate(context.Background(), c, kindcluster, buildKindConfig(kindcluster))
+	want := map[string]int32{portNameAPIServer: 40002, portNameHTTP: 40003}
+
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("allocate() = %v, %v, want %v", got, err, want)
+	}
+
+	// The allocations are kept in the next reconciliations
+	kindcluster.Status.AllocatedPorts = got
+
+	if got, err := allocator.allocate(context.Background(), c, kindcluster, buildKindConfig(kindcluster)); err != nil ||
+		!reflect.DeepEqual(got, want) {
+		t.Errorf("allocate() = %v, %v, want %v", got, err, want)
+	}
+
+	// The reserved ports are not allocated to another cluster until they are released
+	another := newPortTestKINDCluster("another", 0)
+	another.Spec.Ingress = &infrastructurev1alpha1.KINDClusterIngress{}
+
+	if _, err := allocator.allocate(context.Background(), c, another, buildKindConfig(another)); !errors.Is(err, errPortRangeExhausted) {
+		t.Errorf("allocate() error = %v, want %v", err, errPortRangeExhausted)
+	}
+
+	allocator.release("default/test")
+
+	if _, err := allocator.allocate(context.Background(), c, another, buildKindConfig(another)); err != nil {
+		t.Errorf("allocate() error = %v after release", err)
+	}
+}
+
+func Test_AllocatePortsProbeHostPorts(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	// The only port of the range is used by another process of the host
+	listener, err := net.Listen("tcp", ":0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	allocator, err := NewPortAllocator(fmt.Sprintf("%d-%d", port, port))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allocator.ProbeHostPorts = true
+
+	kindcluster := newPortTestKINDCluster("test", 0)
+	c := fake.NewFakeClientWithScheme(testScheme, kindcluster)
+
+	if _, err := allocator.allocate(context.Background(), c, kindcluster, buildKindConfig(kindcluster)); !errors.Is(err, errPortRangeExhausted) {
+		t.Errorf("allocate() error = %v, want %v", err, errPortRangeExhausted)
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+const (
+	// The port of the API server in the node containers
+	apiServerContainerPort = 6443
+
+	// The label of the node that publishes the ingress ports, the ingress controllers
+	// that are deployed for kind select the node with this label
+	ingressReadyLabel = "ingress-ready"
+
+	// The role of the host port of the registry container
+	registryPortRole = "registry"
+
+	// Purposes of the host ports that are allocated to the clusters
+	portNameAPIServer = "apiServer"
+	portNameHTTP      = "http"
+	portNameHTTPS     = "https"
+)
+
+// hostPort is a port that the nodes of a cluster publish on the host
+type hostPort struct {
+	role          string
+	containerPort int32
+	port          int32
+	listenAddress string
+	protocol      string
+
+	// Whether the port is the port of the API server
+	apiServer bool
+
+	// The name of the registry container that publishes the port, the registry is shared by
+	// the clusters that use the same name
+	registry string
+}
+
+func (p hostPort) String() string {
+	return fmt.Sprintf("%s/%s", net.JoinHostPort(p.listenAddress, fmt.Sprint(p.port)), p.protocol)
+}
+
+// Get the host ports of the kind cluster configuration, the ports that are picked randomly by
+// kind are not included because they cannot conflict
+// The API server port is published by the control-plane or the load balancer node on the
+// API server address, the default address of kind is used if it is not specified.
+func getHostPorts(config *v1alpha4.Cluster) []hostPort {
+	var ports []hostPort
+
+	if config.Networking.APIServerPort != 0 {
+		address := config.Networking.APIServerAddress
+
+		if address == "" {
+			defaulted := config.DeepCopy()
+			v1alpha4.SetDefaultsCluster(defaulted)
+			address = defaulted.Networking.APIServerAddress
+		}
+
+		ports = append(ports, hostPort{
+			role:          string(v1alpha4.ControlPlaneRole),
+			containerPort: apiServerContainerPort,
+			port:          config.Networking.APIServerPort,
+			listenAddress: address,
+			protocol:      string(v1alpha4.PortMappingProtocolTCP),
+			apiServer:     true,
+		})
+	}
+
+	for _, node := range config.Nodes {
+		for _, mapping := range node.ExtraPortMappings {
+			if mapping.HostPort == 0 {
+				continue
+			}
+
+			port := hostPort{
+				role:          string(node.Role),
+				containerPort: mapping.ContainerPort,
+				port:          mapping.HostPort,
+				listenAddress: mapping.ListenAddress,
+				protocol:      string(mapping.Protocol),
+			}
+
+			// kind publishes the ports on all addresses with TCP by default
+			if port.listenAddress == "" {
+				port.listenAddress = "0.0.0.0"
+			}
+
+			if port.protocol == "" {
+				port.protocol = string(v1alpha4.PortMappingProtocolTCP)
+			}
+
+			ports = append(ports, port)
+		}
+	}
+
+	return ports
+}
+
+// Get the host port of the local registry of the KINDCluster instance, the registry container
+// publishes it on the loopback address
+func getRegistryHostPorts(kindcluster *infrastructurev1alpha1.KINDCluster) []hostPort {
+	if kindcluster.Spec.Registry == nil {
+		return nil
+	}
+
+	options := getRegistryOptions(kindcluster.Spec.Registry)
+
+	return []hostPort{{
+		role:          registryPortRole,
+		containerPort: backend.RegistryContainerPort,
+		port:          options.HostPort,
+		listenAddress: "127.0.0.1",
+		protocol:      string(v1alpha4.PortMappingProtocolTCP),
+		registry:      options.Name,
+	}}
+}
+
+// Check whether two host ports cannot be published together, they conflict if they use the
+// same port and protocol on the same address, or one of them uses all addresses
+func hostPortsConflict(a, b hostPort) bool {
+	if a.port != b.port || a.protocol != b.protocol {
+		return false
+	}
+
+	addressA, addressB := net.ParseIP(a.listenAddress), net.ParseIP(b.listenAddress)
+
+	if addressA == nil || addressB == nil {
+		return a.listenAddress == b.listenAddress
+	}
+
+	return addressA.IsUnspecified() || addressB.IsUnspecified() || addressA.Equal(addressB)
+}
+
+// Find the host ports of the kind cluster configuration that conflict with each other or
+// with the host ports of the other KINDClusters
+func findHostPortConflicts(ctx context.Context, c client.Reader, kindcluster *infrastructurev1alpha1.KINDCluster,
+	config *v1alpha4.Cluster) ([]string, error) {
+	var conflicts []string
+
+	ports := append(getHostPorts(config), getRegistryHostPorts(kindcluster)...)
+
+	for i := range ports {
+		for j := i + 1; j < len(ports); j++ {
+			if hostPortsConflict(ports[i], ports[j]) {
+				conflicts = append(conflicts, fmt.Sprintf("host port %s is mapped more than once", ports[j]))
+			}
+		}
+	}
+
+	otherPorts, err := listOtherHostPorts(ctx, c, kindcluster)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, owner := range sortedKeys(otherPorts) {
+		for _, port := range ports {
+			for _, otherPort := range otherPorts[owner] {
+				switch {
+				case port.registry != "" && port.registry == otherPort.registry:
+					// The same registry container is shared by the clusters, so it must be
+					// published on the same port for both of them
+					if port.port != otherPort.port {
+						conflicts = append(conflicts, fmt.Sprintf("registry %s is published on host port %d by KINDCluster %s",
+							port.registry, otherPort.port, owner))
+					}
+				case hostPortsConflict(port, otherPort):
+					conflicts = append(conflicts, fmt.Sprintf("host port %s is used by KINDCluster %s", port, owner))
+				}
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// List the host ports of the KINDClusters other than the specified one by their namespaced names
+// The ports are read from the kind cluster configurations with the allocated ports, the
+// registries, and the API server ports that were picked by kind from the status.
+func listOtherHostPorts(ctx context.Context, c client.Reader,
+	kindcluster *infrastructurev1alpha1.KINDCluster) (map[string][]hostPort, error) {
+	var kindclusters infrastructurev1alpha1.KINDClusterList
+
+	if err := c.List(ctx, &kindclusters); err != nil {
+		return nil, err
+	}
+
+	ports := map[string][]hostPort{}
+
+	for i := range kindclusters.Items {
+		other := &kindclusters.Items[i]
+
+		if other.Namespace == kindcluster.Namespace && other.Name == kindcluster.Name {
+			continue
+		}
+
+		otherConfig, err := getKindConfig(ctx, c, other)
+
+		if err != nil {
+			otherConfig = buildKindConfig(other)
+		}
+
+		otherConfig = withPortAllocations(otherConfig, other)
+		otherPorts := append(getHostPorts(otherConfig), getRegistryHostPorts(other)...)
+
+		if networking := other.Status.Networking; networking != nil && otherConfig.Networking.APIServerPort == 0 &&
+			networking.APIServerPort != 0 {
+			otherPorts = append(otherPorts, hostPort{
+				role:          string(v1alpha4.ControlPlaneRole),
+				containerPort: apiServerContainerPort,
+				port:          networking.APIServerPort,
+				listenAddress: networking.APIServerAddress,
+				protocol:      string(v1alpha4.PortMappingProtocolTCP),
+				apiServer:     true,
+			})
+		}
+
+		ports[client.ObjectKeyFromObject(other).String()] = otherPorts
+	}
+
+	return ports, nil
+}
+
+// Add the host ports that were allocated to the KINDCluster instance and its ingress ports
+// to a copy of the kind cluster configuration
+// The ingress ports are published by the first control-plane node, a port of the ingress
+// that is neither specified nor allocated is picked randomly by kind.
+func withPortAllocations(config *v1alpha4.Cluster, kindcluster *infrastructurev1alpha1.KINDCluster) *v1alpha4.Cluster {
+	config = config.DeepCopy()
+	allocated := kindcluster.Status.AllocatedPorts
+
+	if config.Networking.APIServerPort == 0 {
+		config.Networking.APIServerPort = allocated[portNameAPIServer]
+	}
+
+	ingress := kindcluster.Spec.Ingress
+
+	if ingress == nil {
+		return config
+	}
+
+	for i := range config.Nodes {
+		node := &config.Nodes[i]
+
+		if node.Role != v1alpha4.ControlPlaneRole {
+			continue
+		}
+
+		httpPort, httpsPort := ingress.HTTPPort, ingress.HTTPSPort
+
+		if httpPort == 0 {
+			httpPort = allocated[portNameHTTP]
+		}
+
+		if httpsPort == 0 {
+			httpsPort = allocated[portNameHTTPS]
+		}
+
+		node.ExtraPortMappings = append(node.ExtraPortMappings,
+			v1alpha4.PortMapping{ContainerPort: 80, HostPort: httpPort, Protocol: v1alpha4.PortMappingProtocolTCP},
+			v1alpha4.PortMapping{ContainerPort: 443, HostPort: httpsPort, Protocol: v1alpha4.PortMappingProtocolTCP})
+
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+
+		node.Labels[ingressReadyLabel] = "true"
+
+		break
+	}
+
+	return config
+}
+
+// Get the sorted keys of a map
+func sortedKeys(m map[string][]hostPort) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Get the status of the host ports of the kind cluster configuration
+func getPortStatuses(config *v1alpha4.Cluster) []infrastructurev1alpha1.KINDPortStatus {
+	var statuses []infrastructurev1alpha1.KINDPortStatus
+
+	for _, port := range getHostPorts(config) {
+		// The API server port is reported in the networking options
+		if port.apiServer {
+			continue
+		}
+
+		statuses = append(statuses, infrastructurev1alpha1.KINDPortStatus{
+			Role:          port.role,
+			ContainerPort: port.containerPort,
+			HostPort:      port.port,
+			ListenAddress: port.listenAddress,
+			Protocol:      port.protocol,
+		})
+	}
+
+	return statuses
+}
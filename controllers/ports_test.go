@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_HostPortsConflict(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		a        hostPort
+		b        hostPort
+		conflict bool
+	}{
+		{"same address", hostPort{port: 80, listenAddress: "127.0.0.1", protocol: "TCP"},
+			hostPort{port: 80, listenAddress: "127.0.0.1", protocol: "TCP"}, true},
+		{"all addresses", hostPort{port: 80, listenAddress: "0.0.0.0", protocol: "TCP"},
+			hostPort{port: 80, listenAddress: "127.0.0.1", protocol: "TCP"}, true},
+		{"other address", hostPort{port: 80, listenAddress: "127.0.0.2", protocol: "TCP"},
+			hostPort{port: 80, listenAddress: "127.0.0.1", protocol: "TCP"}, false},
+		{"other protocol", hostPort{port: 80, listenAddress: "0.0.0.0", protocol: "UDP"},
+			hostPort{port: 80, listenAddress: "0.0.0.0", protocol: "TCP"}, false},
+		{"other port", hostPort{port: 80, listenAddress: "0.0.0.0", protocol: "TCP"},
+			hostPort{port: 443, listenAddress: "0.0.0.0", protocol: "TCP"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hostPortsConflict(tc.a, tc.b); got != tc.conflict {
+				t.Errorf("hostPortsConflict() = %v, want %v", got, tc.conflict)
+			}
+		})
+	}
+}
+
+func Test_FindHostPortConflicts(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	ingressCluster := newPortTestKINDCluster("ingress", 80)
+
+	// The API server port of this cluster was picked by kind
+	randomPortCluster := newPortTestKINDCluster("random-port", 0)
+	randomPortCluster.Status.Networking = &infrastructurev1alpha1.KINDClusterNetworking{
+		APIServerAddress: "127.0.0.1",
+		APIServerPort:    40000,
+	}
+
+	// This cluster publishes the default registry on the default port
+	registryCluster := newPortTestKINDCluster("registry", 0)
+	registryCluster.Spec.Registry = &infrastructurev1alpha1.KINDClusterRegistry{}
+
+	c := fake.NewFakeClientWithScheme(testScheme, ingressCluster, randomPortCluster, registryCluster)
+
+	var testCases = []struct {
+		name          string
+		hostPort      int32
+		apiServerPort int32
+		registry      *infrastructurev1alpha1.KINDClusterRegistry
+		conflicts     int
+	}{
+		{"free ports", 8080, 40001, nil, 0},
+		{"ingress port of another cluster", 80, 0, nil, 1},
+		{"API server port of another cluster", 8080, 40000, nil, 1},
+		{"API server port mapped twice", 40001, 40001, nil, 1},
+		{"registry port of another cluster", 5001, 40001, nil, 1},
+		{"shared registry", 8080, 40001, &infrastructurev1alpha1.KINDClusterRegistry{}, 0},
+		{"shared registry on another port", 8080, 40001, &infrastructurev1alpha1.KINDClusterRegistry{HostPort: 5002}, 1},
+		{"another registry on the same port", 8080, 40001, &infrastructurev1alpha1.KINDClusterRegistry{Name: "other"}, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kindcluster := newPortTestKINDCluster("test", tc.hostPort)
+			kindcluster.Spec.Networking = &infrastructurev1alpha1.KINDClusterNetworking{APIServerPort: tc.apiServerPort}
+			kindcluster.Spec.Registry = tc.registry
+
+			got, err := findHostPortConflicts(context.Background(), c, kindcluster, buildKindConfig(kindcluster))
+
+			if err != nil || len(got) != tc.conflicts {
+				t.Errorf("findHostPortConflicts() = %v, %v, want %d conflicts", got, err, tc.conflicts)
+			}
+		})
+	}
+}
+
+func newPortTestKINDCluster(name string, hostPort int32) *infrastructurev1alpha1.KINDCluster {
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName:       name,
+			KubernetesVersion: "1.21",
+		},
+	}
+
+	if hostPort != 0 {
+		kindcluster.Spec.Topology = &infrastructurev1alpha1.KINDClusterTopology{
+			ControlPlane: infrastructurev1alpha1.ControlPlaneTopology{Replicas: 1, KINDNodeTemplate: infrastructurev1alpha1.KINDNodeTemplate{
+				ExtraPortMappings: []infrastructurev1alpha1.PortMapping{{ContainerPort: 80, HostPort: hostPort}},
+			}},
+		}
+	}
+
+	return kindcluster
+}
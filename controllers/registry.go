@@ -0,0 +1,122 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+)
+
+const (
+	// Defaults of the registry options, they are the same as in the local registry guide of kind
+	defaultRegistryName     = "kind-registry"
+	defaultRegistryHostPort = 5001
+
+	// Image of the registry containers
+	registryImage = "registry:2"
+
+	// Keys for logs
+	registryNameKey = "registryName"
+
+	// The containerd configuration patch that makes the nodes pull the images of the host
+	// endpoint of the registry from the registry container
+	registryMirrorPatchTemplate = `[plugins."io.containerd.grpc.v1.cri".registry.mirrors."%s"]
+  endpoint = ["http://%s"]`
+
+	// The ConfigMap that announces the local registry to the tools in the cluster, see
+	// https://github.com/kubernetes/enhancements/tree/master/keps/sig-cluster-lifecycle/generic/1755-communicating-a-local-registry
+	localRegistryHostingTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: local-registry-hosting
+  namespace: kube-public
+data:
+  localRegistryHosting.v1: |
+    host: "%s"
+    hostFromContainerRuntime: "%s"
+    help: "https://kind.sigs.k8s.io/docs/user/local-registry/"
+`
+)
+
+// Get the options of the registry container of the KINDCluster instance, the options that
+// are not specified are defaulted
+func getRegistryOptions(registry *infrastructurev1alpha1.KINDClusterRegistry) backend.RegistryOptions {
+	options := backend.RegistryOptions{
+		Name:     registry.Name,
+		HostPort: registry.HostPort,
+		Image:    registryImage,
+	}
+
+	if options.Name == "" {
+		options.Name = defaultRegistryName
+	}
+
+	if options.HostPort == 0 {
+		options.HostPort = defaultRegistryHostPort
+	}
+
+	return options
+}
+
+// Get the endpoints of the registry on the host and in the network of the nodes
+func getRegistryStatus(options backend.RegistryOptions) *infrastructurev1alpha1.KINDRegistryStatus {
+	return &infrastructurev1alpha1.KINDRegistryStatus{
+		Endpoint:         fmt.Sprintf("localhost:%d", options.HostPort),
+		InternalEndpoint: fmt.Sprintf("%s:%d", options.Name, backend.RegistryContainerPort),
+	}
+}
+
+// Get the containerd configuration patch of the registry mirror
+func getRegistryMirrorPatch(registry *infrastructurev1alpha1.KINDClusterRegistry) string {
+	status := getRegistryStatus(getRegistryOptions(registry))
+
+	return fmt.Sprintf(registryMirrorPatchTemplate, status.Endpoint, status.InternalEndpoint)
+}
+
+// Ensure that the registry container of the KINDCluster instance is running and connected
+// to the network of the nodes, and that it is announced in the cluster
+// The announcement is applied once, when the endpoints of the registry in the status change.
+func ensureRegistry(clusterBackend backend.ClusterBackend, kindcluster *infrastructurev1alpha1.KINDCluster,
+	log logr.Logger) error {
+	options := getRegistryOptions(kindcluster.Spec.Registry)
+
+	if err := clusterBackend.EnsureRegistry(options); err != nil {
+		return err
+	}
+
+	status := getRegistryStatus(options)
+
+	if current := kindcluster.Status.Registry; current != nil && *current == *status {
+		return nil
+	}
+
+	clusterName := kindcluster.Spec.ClusterName
+
+	if err := clusterBackend.ApplyManifest(clusterName,
+		fmt.Sprintf(localRegistryHostingTemplate, status.Endpoint, status.InternalEndpoint)); err != nil {
+		return err
+	}
+
+	log.Info("Registry is announced in cluster", clusterNameKey, clusterName, registryNameKey, options.Name)
+
+	kindcluster.Status.Registry = status
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func Test_GetRegistryMirrorPatch(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		registry *infrastructurev1alpha1.KINDClusterRegistry
+		want     string
+	}{
+		{"defaults", &infrastructurev1alpha1.KINDClusterRegistry{},
+			"[plugins.\"io.containerd.grpc.v1.cri\".registry.mirrors.\"localhost:5001\"]\n  endpoint = [\"http://kind-registry:5000\"]"},
+		{"custom registry", &infrastructurev1alpha1.KINDClusterRegistry{Name: "team-registry", HostPort: 5002},
+			"[plugins.\"io.containerd.grpc.v1.cri\".registry.mirrors.\"localhost:5002\"]\n  endpoint = [\"http://team-registry:5000\"]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getRegistryMirrorPatch(tc.registry); got != tc.want {
+				t.Errorf("getRegistryMirrorPatch() = %v, want %v", got, tc.want)
+			}
+
+			kindcluster := &infrastructurev1alpha1.KINDCluster{
+				Spec: infrastructurev1alpha1.KINDClusterSpec{KubernetesVersion: "1.21", Registry: tc.registry},
+			}
+
+			if got := buildKindConfig(kindcluster).ContainerdConfigPatches; !reflect.DeepEqual(got, []string{tc.want}) {
+				t.Errorf("buildKindConfig() containerdConfigPatches = %v, want %v", got, []string{tc.want})
+			}
+		})
+	}
+}
+
+func Test_EnsureRegistry(t *testing.T) {
+	b := backend.NewFakeBackend()
+
+	if err := b.Create("test", nil, backend.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	kindcluster := &infrastructurev1alpha1.KINDCluster{
+		Spec: infrastructurev1alpha1.KINDClusterSpec{
+			ClusterName: "test",
+			Registry:    &infrastructurev1alpha1.KINDClusterRegistry{},
+		},
+	}
+
+	// The registry is announced once, the next reconciliations only ensure the container
+	for i := 0; i < 2; i++ {
+		if err := ensureRegistry(b, kindcluster, ctrl.Log); err != nil {
+			t.Fatalf("ensureRegistry() error = %v", err)
+		}
+	}
+
+	want := &infrastructurev1alpha1.KINDRegistryStatus{Endpoint: "localhost:5001", InternalEndpoint: "kind-registry:5000"}
+
+	if !reflect.DeepEqual(kindcluster.Status.Registry, want) {
+		t.Errorf("ensureRegistry() status = %v, want %v", kindcluster.Status.Registry, want)
+	}
+
+	if _, ok := b.Registries()["kind-registry"]; !ok || len(b.Manifests("test")) != 1 {
+		t.Errorf("ensureRegistry() registries = %v, %d manifests, want kind-registry and 1 manifest",
+			b.Registries(), len(b.Manifests("test")))
+	}
+
+	// The shared registry container cannot be published on another port
+	kindcluster.Spec.Registry.HostPort = 5002
+
+	if err := ensureRegistry(b, kindcluster, ctrl.Log); err == nil {
+		t.Errorf("ensureRegistry() error = nil, want an error for the host port of the existing registry")
+	}
+}
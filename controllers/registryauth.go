@@ -0,0 +1,111 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Read the registry credentials of the Secret that the KINDCluster instance refers to
+// The errors do not contain the content of the Secret, so they can be logged and reported
+// in the status.
+func readRegistryAuth(ctx context.Context, c client.Reader, kindcluster *infrastructurev1alpha1.KINDCluster) ([]byte, error) {
+	secretName := kindcluster.Spec.RegistryAuth.SecretName
+	secret := &corev1.Secret{}
+
+	if err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: kindcluster.Namespace}, secret); err != nil {
+		return nil, fmt.Errorf("registry credentials secret %s cannot be fetched: %w", secretName, err)
+	}
+
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+
+	if !ok {
+		return nil, fmt.Errorf("registry credentials secret %s does not have the %s key", secretName, corev1.DockerConfigJsonKey)
+	}
+
+	return data, nil
+}
+
+// Get the hash of the registry credentials and the names of the nodes, it changes when the
+// Secret is rotated or a node is added, for example by a KINDMachine
+func hashRegistryAuth(data []byte, nodes []backend.Node) string {
+	hash := sha256.New()
+	hash.Write(data)
+
+	for _, name := range getNodeNames(nodes) {
+		hash.Write([]byte{0})
+		hash.Write([]byte(name))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Ensure that the registry credentials of the KINDCluster instance are written into the
+// nodes of the cluster and report it in the RegistryAuthApplied condition
+// The credentials are copied into the node containers instead of being mounted from the
+// host, because the controller does not need to share a filesystem with the container
+// runtime. They are written again only if the Secret or the nodes changed.
+func ensureRegistryAuth(ctx context.Context, c client.Reader, clusterBackend backend.ClusterBackend,
+	kindcluster *infrastructurev1alpha1.KINDCluster, nodes []backend.Node, log logr.Logger) error {
+	if kindcluster.Spec.RegistryAuth == nil {
+		kindcluster.Status.RegistryAuthHash = ""
+		meta.RemoveStatusCondition(&kindcluster.Status.Conditions, infrastructurev1alpha1.RegistryAuthAppliedCondition)
+
+		return nil
+	}
+
+	conditions := clusterConditions(kindcluster)
+	clusterName := kindcluster.Spec.ClusterName
+
+	data, err := readRegistryAuth(ctx, c, kindcluster)
+
+	if err == nil {
+		hash := hashRegistryAuth(data, nodes)
+
+		if hash == kindcluster.Status.RegistryAuthHash {
+			return nil
+		}
+
+		if err = clusterBackend.WriteRegistryAuth(clusterName, data); err == nil {
+			log.Info("Registry credentials are written into nodes", clusterNameKey, clusterName,
+				secretNameKey, kindcluster.Spec.RegistryAuth.SecretName)
+
+			kindcluster.Status.RegistryAuthHash = hash
+
+			conditions.set(infrastructurev1alpha1.RegistryAuthAppliedCondition, metav1.ConditionTrue, reasonCredentialsWritten,
+				fmt.Sprintf("Registry credentials of secret %s are written into %d nodes", kindcluster.Spec.RegistryAuth.SecretName, countKubernetesNodes(nodes)))
+
+			return nil
+		}
+	}
+
+	conditions.set(infrastructurev1alpha1.RegistryAuthAppliedCondition, metav1.ConditionFalse, reasonRegistryAuthError, err.Error())
+
+	return err
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_EnsureRegistryAuth(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	utilruntime.Must(scheme.AddToScheme(testScheme))
+	utilruntime.Must(infrastructurev1alpha1.AddToScheme(testScheme))
+
+	credentials := []byte(`{"auths":{"mirror.internal":{"auth":"dXNlcjpwYXNzd29yZA=="}}}`)
+
+	c := fake.NewFakeClientWithScheme(testScheme,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-auth", Namespace: defaultNamespace},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: credentials},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: defaultNamespace},
+			Data:       map[string][]byte{"password": []byte("password")},
+		})
+
+	var testCases = []struct {
+		name       string
+		secretName string
+		wantErr    bool
+	}{
+		{"docker config secret", "registry-auth", false},
+		{"secret without docker config", "opaque", true},
+		{"missing secret", "missing", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := backend.NewFakeBackend()
+
+			if err := b.Create("test", nil, backend.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			kindcluster := &infrastructurev1alpha1.KINDCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: defaultNamespace},
+				Spec: infrastructurev1alpha1.KINDClusterSpec{
+					ClusterName:  "test",
+					RegistryAuth: &infrastructurev1alpha1.RegistryAuthReference{SecretName: tc.secretName},
+				},
+			}
+
+			nodes, _ := b.ListNodes("test")
+
+			err := ensureRegistryAuth(context.Background(), c, b, kindcluster, nodes, ctrl.Log)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ensureRegistryAuth() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			applied := meta.IsStatusConditionTrue(kindcluster.Status.Conditions, infrastructurev1alpha1.RegistryAuthAppliedCondition)
+
+			if applied == tc.wantErr || (string(b.RegistryAuth("test")) == string(credentials)) == tc.wantErr {
+				t.Errorf("ensureRegistryAuth() conditions = %v, want the credentials written %v",
+					kindcluster.Status.Conditions, !tc.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			// The credentials are written again when a node is added
+			previous := kindcluster.Status.RegistryAuthHash
+
+			if _, err := b.CreateNode("test", backend.NodeOptions{Name: "test-worker", Role: "worker"}); err != nil {
+				t.Fatal(err)
+			}
+
+			nodes, _ = b.ListNodes("test")
+
+			if err := ensureRegistryAuth(context.Background(), c, b, kindcluster, nodes, ctrl.Log); err != nil ||
+				kindcluster.Status.RegistryAuthHash == previous {
+				t.Errorf("ensureRegistryAuth() error = %v, hash = %v, want a new hash", err, kindcluster.Status.RegistryAuthHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// The field manager of the objects that the controller applies into the workload clusters
+	workloadFieldManager = "cluster-api-provider-kind"
+
+	// The timeout of the requests to the API servers of the workload clusters
+	workloadRequestTimeout = 10 * time.Second
+)
+
+// workloadCluster is a connection to the API server of a workload cluster
+type workloadCluster interface {
+	// Apply applies the object with server-side apply, the object takes the ownership of
+	// the conflicting fields
+	Apply(ctx context.Context, obj *unstructured.Unstructured) error
+
+	// Readyz returns an error if the readiness endpoint of the API server is not healthy
+	Readyz(ctx context.Context) error
+
+	// ListNodes lists the nodes that are registered in the cluster
+	ListNodes(ctx context.Context) ([]corev1.Node, error)
+}
+
+// workloadClusterFactory connects to a workload cluster with its kubeconfig
+type workloadClusterFactory func(kubeconfig []byte) (workloadCluster, error)
+
+// kubeWorkloadCluster is the connection to a workload cluster through its API server
+type kubeWorkloadCluster struct {
+	client    client.Client
+	mapper    meta.RESTMapper
+	discovery discovery.DiscoveryInterface
+}
+
+// Connect to the workload cluster with the kubeconfig
+func newKubeWorkloadCluster(kubeconfig []byte) (workloadCluster, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config.Timeout = workloadRequestTimeout
+
+	// The mapper discovers the kinds of the cluster lazily, so the custom resources of the
+	// CRDs that were applied before can be applied
+	mapper, err := apiutil.NewDynamicRESTMapper(config, apiutil.WithLazyDiscovery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := client.New(config, client.Options{Mapper: mapper})
+
+	if err != nil {
+		return nil, err
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubeWorkloadCluster{client: c, mapper: mapper, discovery: discoveryClient}, nil
+}
+
+// workloadClusterCache keeps the connections to the workload clusters by the cluster names,
+// so that their REST mappers and discovery clients are not created on every reconciliation
+type workloadClusterCache struct {
+	mu       sync.Mutex
+	clusters map[string]cachedWorkloadCluster
+}
+
+// cachedWorkloadCluster is a connection to a workload cluster with the hash of the kubeconfig
+// that it was opened with
+type cachedWorkloadCluster struct {
+	kubeconfigHash string
+	workload       workloadCluster
+}
+
+// Get the connection to the cluster, it is not returned if it was opened with another kubeconfig
+func (c *workloadClusterCache) get(clusterName, kubeconfigHash string) (workloadCluster, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.clusters[clusterName]
+
+	if !ok || cached.kubeconfigHash != kubeconfigHash {
+		return nil, false
+	}
+
+	return cached.workload, true
+}
+
+// Store the connection to the cluster, it replaces the connection of the previous kubeconfig
+func (c *workloadClusterCache) set(clusterName, kubeconfigHash string, workload workloadCluster) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clusters == nil {
+		c.clusters = map[string]cachedWorkloadCluster{}
+	}
+
+	c.clusters[clusterName] = cachedWorkloadCluster{kubeconfigHash: kubeconfigHash, workload: workload}
+}
+
+// Remove the connection to the cluster, for example when the cluster is deleted
+func (c *workloadClusterCache) remove(clusterName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.clusters, clusterName)
+}
+
+// Get the workload cluster of the kubeconfig with the factory of the reconciler, the
+// cluster is connected through its API server if the factory is not set
+// The connection is cached until the kubeconfig of the cluster changes, for example when
+// the cluster is recreated.
+func (r *KINDClusterReconciler) getWorkloadCluster(clusterName string, kubeconfig []byte) (workloadCluster, error) {
+	kubeconfigHash := hashKubeconfig(kubeconfig)
+
+	if workload, ok := r.workloadClusters.get(clusterName, kubeconfigHash); ok {
+		return workload, nil
+	}
+
+	newWorkloadCluster := r.newWorkloadCluster
+
+	if newWorkloadCluster == nil {
+		newWorkloadCluster = newKubeWorkloadCluster
+	}
+
+	workload, err := newWorkloadCluster(kubeconfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.workloadClusters.set(clusterName, kubeconfigHash, workload)
+
+	return workload, nil
+}
+
+// Apply applies the object with server-side apply, a namespaced object without a namespace
+// is applied to the default namespace as kubectl does
+func (w *kubeWorkloadCluster) Apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+
+	mapping, err := w.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+
+	if err != nil {
+		return err
+	}
+
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() == "" {
+		obj.SetNamespace("default")
+	}
+
+	return w.client.Patch(ctx, obj, client.Apply, client.FieldOwner(workloadFieldManager), client.ForceOwnership)
+}
+
+// Readyz requests the readiness endpoint of the API server, the body of the response lists
+// the failed checks if it is not healthy
+func (w *kubeWorkloadCluster) Readyz(ctx context.Context) error {
+	body, err := w.discovery.RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+
+	if err != nil && len(body) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(body)))
+	}
+
+	return err
+}
+
+// ListNodes lists the nodes that are registered in the cluster
+func (w *kubeWorkloadCluster) ListNodes(ctx context.Context) ([]corev1.Node, error) {
+	var nodes corev1.NodeList
+
+	if err := w.client.List(ctx, &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes.Items, nil
+}
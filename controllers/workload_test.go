@@ -0,0 +1,45 @@
+package controllers
+
+import "testing"
+
+func Test_GetWorkloadCluster(t *testing.T) {
+	connections := 0
+
+	r := &KINDClusterReconciler{
+		newWorkloadCluster: func(kubeconfig []byte) (workloadCluster, error) {
+			connections++
+
+			return &fakeWorkloadCluster{}, nil
+		},
+	}
+
+	var testCases = []struct {
+		name        string
+		clusterName string
+		kubeconfig  string
+		connections int
+	}{
+		{"new cluster", "test", "kubeconfigData", 1},
+		{"same kubeconfig", "test", "kubeconfigData", 1},
+		{"changed kubeconfig", "test", "newKubeconfigData", 2},
+		{"another cluster", "other", "newKubeconfigData", 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := r.getWorkloadCluster(tc.clusterName, []byte(tc.kubeconfig)); err != nil {
+				t.Fatal(err)
+			}
+
+			if connections != tc.connections {
+				t.Errorf("getWorkloadCluster() connections = %d, want %d", connections, tc.connections)
+			}
+		})
+	}
+
+	// The connection of a deleted cluster is opened again
+	r.workloadClusters.remove("test")
+
+	if _, err := r.getWorkloadCluster("test", []byte("newKubeconfigData")); err != nil || connections != 4 {
+		t.Errorf("getWorkloadCluster() = %v, connections = %d, want a new connection", err, connections)
+	}
+}
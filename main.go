@@ -18,7 +18,9 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -33,6 +35,7 @@ import (
 
 	infrastructurev1alpha1 "github.com/sergenyalcin/cluster-api-provider-kind/api/v1alpha1"
 	"github.com/sergenyalcin/cluster-api-provider-kind/controllers"
+	"github.com/sergenyalcin/cluster-api-provider-kind/pkg/backend"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,11 +55,45 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var clusterBackend string
+	var maxConcurrentReconciles int
+	var legacyKubeconfigSecret bool
+	var defaultKubernetesVersion string
+	var hostPortRange string
+	var probeHostPorts bool
+	var probeClusterHealth bool
+	var healthCheckInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&clusterBackend, "cluster-backend", "kind",
+		"The backend that manages the workload clusters. "+
+			"One of: kind, fake. The fake backend keeps the clusters in memory and does not need a container runtime.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The maximum number of KINDClusters and KINDMachines that are reconciled in parallel. "+
+			"The clusters are created in the background, so a higher value mainly speeds up the other reconciliation steps.")
+	flag.BoolVar(&legacyKubeconfigSecret, "legacy-kubeconfig-secret", true,
+		"Also store the kubeconfig of each cluster in the <clusterName>-config secret with the config key, "+
+			"in addition to the Cluster API <name>-kubeconfig secret.")
+	flag.StringVar(&defaultKubernetesVersion, "default-kubernetes-version", infrastructurev1alpha1.DefaultKubernetesVersion,
+		"The kubernetes version of the KINDClusters that do not specify a version. "+
+			"It must be a built-in version or a version of a KINDImageCatalog.")
+	flag.StringVar(&hostPortRange, "host-port-range", "",
+		"The range of the host ports that are allocated to the API servers and the ingresses of the clusters "+
+			"that do not specify their ports, in the first-last format, for example 40000-40999. "+
+			"If it is empty, kind picks random ports.")
+	flag.BoolVar(&probeHostPorts, "probe-host-ports", true,
+		"Listen on a port of the host port range before it is allocated, so that the ports that other processes use "+
+			"are not allocated. Disable it if the controller does not run on the host of the container runtime.")
+	flag.BoolVar(&probeClusterHealth, "probe-cluster-health", true,
+		"Probe the API servers and the nodes of the provisioned clusters, a cluster is ready only if it is healthy. "+
+			"If it is disabled, the clusters are ready when they exist. "+
+			"It is always disabled with the fake backend, because its clusters do not have API servers.")
+	flag.DurationVar(&healthCheckInterval, "health-check-interval", 30*time.Second,
+		"The interval with which the health of the provisioned clusters is probed again. "+
+			"If it is zero, the health is only probed when the clusters are reconciled for other reasons.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +102,9 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// The version is checked against the image catalogs when the KINDClusters are validated
+	infrastructurev1alpha1.DefaultKubernetesVersion = defaultKubernetesVersion
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -78,14 +118,61 @@ func main() {
 		os.Exit(1)
 	}
 
+	var b backend.ClusterBackend
+
+	switch clusterBackend {
+	case "kind":
+		b = backend.NewKindBackend()
+	case "fake":
+		b = backend.NewFakeBackend()
+		probeClusterHealth = false
+	default:
+		setupLog.Error(fmt.Errorf("unknown cluster backend %q", clusterBackend), "unable to create cluster backend")
+		os.Exit(1)
+	}
+
+	var portAllocator *controllers.PortAllocator
+
+	if hostPortRange != "" {
+		if portAllocator, err = controllers.NewPortAllocator(hostPortRange); err != nil {
+			setupLog.Error(err, "unable to create port allocator")
+			os.Exit(1)
+		}
+
+		portAllocator.ProbeHostPorts = probeHostPorts
+	}
+
 	if err = (&controllers.KINDClusterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName(infrastructurev1alpha1.KindOfKindCluster),
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Log:                     ctrl.Log.WithName(infrastructurev1alpha1.KindOfKindCluster),
+		Backend:                 b,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		LegacyKubeconfigSecret:  legacyKubeconfigSecret,
+		PortAllocator:           portAllocator,
+		ProbeClusterHealth:      probeClusterHealth,
+		HealthCheckInterval:     healthCheckInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", infrastructurev1alpha1.KindOfKindCluster)
 		os.Exit(1)
 	}
+	if err = (&controllers.KINDMachineReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Log:                     ctrl.Log.WithName(infrastructurev1alpha1.KindOfKindMachine),
+		Backend:                 b,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", infrastructurev1alpha1.KindOfKindMachine)
+		os.Exit(1)
+	}
+	// The webhooks need a serving certificate, they can be disabled to run the manager locally
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&infrastructurev1alpha1.KINDCluster{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", infrastructurev1alpha1.KindOfKindCluster)
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
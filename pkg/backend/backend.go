@@ -0,0 +1,220 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backend contains the implementations that the controller uses to
+// manage the lifecycles of the workload clusters
+package backend
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+const (
+	// Role of the load balancer node that kind adds in front of multiple control-plane nodes
+	externalLoadBalancerRole = "external-load-balancer"
+
+	// RegistryContainerPort is the port that the local registry listens on in its container
+	RegistryContainerPort = 5000
+)
+
+// ClusterBackend is the interface that creates, deletes and lists the workload clusters
+type ClusterBackend interface {
+	// List returns the names of the existing clusters
+	List() ([]string, error)
+
+	// Create creates a cluster with the specified name and configuration
+	Create(name string, config *v1alpha4.Cluster, options CreateOptions) error
+
+	// Delete deletes the cluster with the specified name
+	// It does not return an error when the cluster does not exist
+	Delete(name string) error
+
+	// KubeConfig returns the kubeconfig of the cluster, if internal is true,
+	// the kubeconfig is the one that can be used from the network of the nodes
+	KubeConfig(name string, internal bool) (string, error)
+
+	// ListNodes returns the nodes of the cluster
+	ListNodes(name string) ([]Node, error)
+
+	// CreateNode creates a node container and joins it to the existing cluster
+	CreateNode(clusterName string, options NodeOptions) (Node, error)
+
+	// DeleteNode removes the node from the cluster and deletes its container
+	// It does not return an error when the node does not exist
+	DeleteNode(clusterName, nodeName string) error
+
+	// EnsureRegistry creates the registry container if it does not exist, starts it if it
+	// is stopped and connects it to the network of the nodes
+	// The registry is shared by the clusters, so it is not deleted with them. It returns an
+	// error if the existing registry is published on another host port.
+	EnsureRegistry(options RegistryOptions) error
+
+	// ApplyManifest applies the YAML manifest to the cluster
+	ApplyManifest(clusterName, manifest string) error
+
+	// LoadImage loads an image into all Kubernetes nodes of the cluster, the image is either
+	// the reference of an image of the container runtime of the host or the absolute path of
+	// an image archive on the host
+	LoadImage(clusterName, image string) error
+
+	// WriteRegistryAuth writes the credentials of the private registries in the docker config
+	// format into the kubelet of all Kubernetes nodes of the cluster and restarts the kubelets
+	// The errors do not contain the credentials.
+	WriteRegistryAuth(clusterName string, dockerConfig []byte) error
+}
+
+// RegistryOptions defines a local registry container
+type RegistryOptions struct {
+	// Name of the registry container, the nodes reach the registry with this name
+	Name string
+
+	// Port on the host that the registry is published on, on the loopback address
+	HostPort int32
+
+	// Image of the registry container
+	Image string
+}
+
+// NodeOptions defines a node that is added to an existing cluster
+type NodeOptions struct {
+	// Name of the node container, it is also the name of the Kubernetes node
+	Name string
+
+	// Role of the node: control-plane or worker
+	Role string
+
+	// Image of the node container
+	Image string
+
+	// Labels of the Kubernetes node, they must be valid Kubernetes labels
+	Labels map[string]string
+
+	// Host paths that are mounted into the node container
+	ExtraMounts []v1alpha4.Mount
+
+	// Name of the machine that the node belongs to
+	Machine string
+}
+
+// CreateOptions defines the options of the cluster creation
+type CreateOptions struct {
+	// OnStep is called with a short description of each step of the creation,
+	// so that the progress of a long running creation can be reported
+	OnStep func(step string)
+}
+
+// Node represents a node of a workload cluster
+type Node struct {
+	// Name of the node container
+	Name string
+
+	// Role of the node: control-plane, worker or external-load-balancer
+	Role string
+
+	// Kubernetes version that is installed on the node, it is empty for
+	// the nodes that do not run Kubernetes such as the load balancer
+	KubernetesVersion string
+
+	// IP addresses of the node in the network of the nodes
+	IPv4 string
+	IPv6 string
+
+	// Name of the machine that the node belongs to, it is empty for
+	// the nodes that were created with the cluster
+	Machine string
+
+	// Provider ID that the kubelet of the node is configured with, it is empty
+	// for the nodes that do not run Kubernetes
+	ProviderID string
+}
+
+// IsImageArchive returns true if the image to load is the path of an image archive
+// instead of an image reference
+func IsImageArchive(image string) bool {
+	return filepath.IsAbs(image)
+}
+
+// ValidateNodeLabels returns an error if a label of a node is not a valid Kubernetes label,
+// the labels are passed to the kubelet in a flag that other labels could be injected into
+func ValidateNodeLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+
+	for key := range labels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid node label key %q: %s", key, strings.Join(errs, "; "))
+		}
+
+		if errs := validation.IsValidLabelValue(labels[key]); len(errs) > 0 {
+			return fmt.Errorf("invalid value of node label %s: %s", key, strings.Join(errs, "; "))
+		}
+	}
+
+	return nil
+}
+
+// ValidateNodeMounts returns an error if a mount of a node does not use absolute paths,
+// kind resolves relative host paths against the working directory of the controller
+func ValidateNodeMounts(mounts []v1alpha4.Mount) error {
+	for _, mount := range mounts {
+		if !filepath.IsAbs(mount.HostPath) {
+			return fmt.Errorf("host path %q of mount is not an absolute path", mount.HostPath)
+		}
+
+		if !path.IsAbs(mount.ContainerPath) {
+			return fmt.Errorf("container path %q of mount is not an absolute path", mount.ContainerPath)
+		}
+	}
+
+	return nil
+}
+
+// ProviderID returns the provider ID of a node, in the format that kind
+// configures for the kubelet: kind://<provider>/<cluster>/<node>
+func ProviderID(provider, clusterName, nodeName string) string {
+	return fmt.Sprintf("kind://%s/%s/%s", provider, clusterName, nodeName)
+}
+
+// ImageVersion returns the version in the tag of a node image reference,
+// for example v1.21.1 for kindest/node:v1.21.1@sha256:...
+func ImageVersion(image string) string {
+	// Strip the digest
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	// The tag is after the last colon that comes after the last slash,
+	// a colon before the slash belongs to the registry port
+	i := strings.LastIndex(image, ":")
+
+	if i < 0 || i < strings.LastIndex(image, "/") {
+		return ""
+	}
+
+	return image[i+1:]
+}
@@ -0,0 +1,405 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+const (
+	// Provider of the nodes in their provider IDs
+	fakeProvider = "fake"
+
+	fakeKubeconfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://%s:6443
+  name: kind-%s
+contexts:
+- context:
+    cluster: kind-%s
+    user: kind-%s
+  name: kind-%s
+current-context: kind-%s
+users:
+- name: kind-%s
+`
+)
+
+// FakeBackend is an in-memory ClusterBackend implementation, it does not need a
+// container runtime so it can be used to run the controller in tests and CI
+type FakeBackend struct {
+	mu       sync.Mutex
+	clusters map[string]*v1alpha4.Cluster
+
+	// Nodes that were added to the clusters after their creation
+	nodes map[string][]Node
+
+	// Registries by their names
+	registries map[string]RegistryOptions
+
+	// Manifests that were applied to the clusters
+	manifests map[string][]string
+
+	// Images that were loaded into the clusters
+	images map[string][]string
+
+	// Registry credentials that were written into the nodes of the clusters
+	registryAuths map[string][]byte
+
+	// CreateError is returned from Create when it is set
+	CreateError error
+
+	// DeleteError is returned from Delete when it is set
+	DeleteError error
+
+	// CreateNodeError is returned from CreateNode when it is set
+	CreateNodeError error
+
+	// LoadImageErrors are returned from LoadImage for the images that they are set for
+	LoadImageErrors map[string]error
+}
+
+// NewFakeBackend returns an empty in-memory ClusterBackend
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		clusters:      map[string]*v1alpha4.Cluster{},
+		nodes:         map[string][]Node{},
+		registries:    map[string]RegistryOptions{},
+		manifests:     map[string][]string{},
+		images:        map[string][]string{},
+		registryAuths: map[string][]byte{},
+	}
+}
+
+// List returns the names of the clusters in memory
+func (b *FakeBackend) List() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names := make([]string, 0, len(b.clusters))
+
+	for name := range b.clusters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Create stores the cluster configuration in memory
+func (b *FakeBackend) Create(name string, config *v1alpha4.Cluster, options CreateOptions) error {
+	if options.OnStep != nil {
+		options.OnStep("Creating cluster in memory")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.CreateError != nil {
+		return b.CreateError
+	}
+
+	if _, ok := b.clusters[name]; ok {
+		return fmt.Errorf("node(s) already exist for a cluster with the name %q", name)
+	}
+
+	if config == nil {
+		config = &v1alpha4.Cluster{}
+	}
+
+	b.clusters[name] = config.DeepCopy()
+
+	return nil
+}
+
+// Delete removes the cluster from memory
+func (b *FakeBackend) Delete(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.DeleteError != nil {
+		return b.DeleteError
+	}
+
+	delete(b.clusters, name)
+	delete(b.nodes, name)
+	delete(b.manifests, name)
+	delete(b.images, name)
+	delete(b.registryAuths, name)
+
+	return nil
+}
+
+// KubeConfig returns a fake kubeconfig of the cluster
+func (b *FakeBackend) KubeConfig(name string, internal bool) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.clusters[name]; !ok {
+		return "", fmt.Errorf("could not locate any control plane nodes for cluster named %q", name)
+	}
+
+	return fakeKubeconfig(name, internal), nil
+}
+
+// ListNodes returns the nodes of the cluster, they are named in the same way
+// as the kind tool names its node containers
+func (b *FakeBackend) ListNodes(name string) ([]Node, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	config, ok := b.clusters[name]
+
+	if !ok {
+		return []Node{}, nil
+	}
+
+	configNodes := config.Nodes
+
+	// kind creates a single control-plane node if no node is configured
+	if len(configNodes) == 0 {
+		configNodes = []v1alpha4.Node{{Role: v1alpha4.ControlPlaneRole}}
+	}
+
+	var nodes []Node
+
+	counts := map[string]int{}
+
+	for _, configNode := range configNodes {
+		role := string(configNode.Role)
+		counts[role]++
+
+		// kind uses its default node image if no image is configured
+		image := configNode.Image
+
+		if image == "" {
+			image = defaults.Image
+		}
+
+		nodeName := fakeNodeName(name, role, counts[role])
+
+		nodes = append(nodes, Node{
+			Name:              nodeName,
+			Role:              role,
+			KubernetesVersion: ImageVersion(image),
+			ProviderID:        ProviderID(fakeProvider, name, nodeName),
+		})
+	}
+
+	if counts[string(v1alpha4.ControlPlaneRole)] > 1 {
+		nodes = append(nodes, Node{
+			Name: fakeNodeName(name, externalLoadBalancerRole, 1),
+			Role: externalLoadBalancerRole,
+		})
+	}
+
+	return append(nodes, b.nodes[name]...), nil
+}
+
+// CreateNode adds a node to the cluster in memory
+func (b *FakeBackend) CreateNode(clusterName string, options NodeOptions) (Node, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.CreateNodeError != nil {
+		return Node{}, b.CreateNodeError
+	}
+
+	if err := ValidateNodeLabels(options.Labels); err != nil {
+		return Node{}, err
+	}
+
+	if err := ValidateNodeMounts(options.ExtraMounts); err != nil {
+		return Node{}, err
+	}
+
+	if _, ok := b.clusters[clusterName]; !ok {
+		return Node{}, fmt.Errorf("cluster %q does not exist", clusterName)
+	}
+
+	for _, node := range b.nodes[clusterName] {
+		if node.Name == options.Name {
+			return Node{}, fmt.Errorf("node %s already exists", options.Name)
+		}
+	}
+
+	image := options.Image
+
+	if image == "" {
+		image = defaults.Image
+	}
+
+	node := Node{
+		Name:              options.Name,
+		Role:              options.Role,
+		KubernetesVersion: ImageVersion(image),
+		IPv4:              fmt.Sprintf("172.18.1.%d", len(b.nodes[clusterName])+1),
+		Machine:           options.Machine,
+		ProviderID:        ProviderID(fakeProvider, clusterName, options.Name),
+	}
+
+	b.nodes[clusterName] = append(b.nodes[clusterName], node)
+
+	return node, nil
+}
+
+// DeleteNode removes a node that was added to the cluster from memory
+func (b *FakeBackend) DeleteNode(clusterName, nodeName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	nodes := b.nodes[clusterName][:0]
+
+	for _, node := range b.nodes[clusterName] {
+		if node.Name != nodeName {
+			nodes = append(nodes, node)
+		}
+	}
+
+	b.nodes[clusterName] = nodes
+
+	return nil
+}
+
+// EnsureRegistry stores the registry in memory, an existing registry is kept
+func (b *FakeBackend) EnsureRegistry(options RegistryOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	existing, ok := b.registries[options.Name]
+
+	if !ok {
+		b.registries[options.Name] = options
+	} else if existing.HostPort != options.HostPort {
+		return fmt.Errorf("registry container %s is published on host port %d, not %d",
+			options.Name, existing.HostPort, options.HostPort)
+	}
+
+	return nil
+}
+
+// Registries returns the registries in memory by their names
+func (b *FakeBackend) Registries() map[string]RegistryOptions {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	registries := make(map[string]RegistryOptions, len(b.registries))
+
+	for name, registry := range b.registries {
+		registries[name] = registry
+	}
+
+	return registries
+}
+
+// ApplyManifest stores the manifest in memory
+func (b *FakeBackend) ApplyManifest(clusterName, manifest string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.clusters[clusterName]; !ok {
+		return fmt.Errorf("cluster %q does not exist", clusterName)
+	}
+
+	b.manifests[clusterName] = append(b.manifests[clusterName], manifest)
+
+	return nil
+}
+
+// Manifests returns the manifests that were applied to the cluster
+func (b *FakeBackend) Manifests(clusterName string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.manifests[clusterName]...)
+}
+
+// LoadImage stores the image of the cluster in memory
+func (b *FakeBackend) LoadImage(clusterName, image string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.LoadImageErrors[image]; err != nil {
+		return err
+	}
+
+	if _, ok := b.clusters[clusterName]; !ok {
+		return fmt.Errorf("cluster %q does not exist", clusterName)
+	}
+
+	b.images[clusterName] = append(b.images[clusterName], image)
+
+	return nil
+}
+
+// Images returns the images that were loaded into the cluster
+func (b *FakeBackend) Images(clusterName string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.images[clusterName]...)
+}
+
+// WriteRegistryAuth stores the registry credentials of the cluster in memory
+func (b *FakeBackend) WriteRegistryAuth(clusterName string, dockerConfig []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.clusters[clusterName]; !ok {
+		return fmt.Errorf("cluster %q does not exist", clusterName)
+	}
+
+	b.registryAuths[clusterName] = append([]byte(nil), dockerConfig...)
+
+	return nil
+}
+
+// RegistryAuth returns the registry credentials that were written into the cluster
+func (b *FakeBackend) RegistryAuth(clusterName string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.registryAuths[clusterName]
+}
+
+// Get the name of a node in the kind format: <cluster>-<role>, <cluster>-<role>2, ...
+func fakeNodeName(clusterName, role string, index int) string {
+	if index == 1 {
+		return fmt.Sprintf("%s-%s", clusterName, role)
+	}
+
+	return fmt.Sprintf("%s-%s%d", clusterName, role, index)
+}
+
+// Get a fake kubeconfig of the cluster
+func fakeKubeconfig(name string, internal bool) string {
+	server := "127.0.0.1"
+
+	if internal {
+		server = fakeNodeName(name, string(v1alpha4.ControlPlaneRole), 1)
+	}
+
+	return fmt.Sprintf(fakeKubeconfigTemplate, server, name, name, name, name, name, name)
+}
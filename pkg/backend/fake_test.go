@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+func Test_FakeBackendListNodes(t *testing.T) {
+	b := NewFakeBackend()
+
+	config := &v1alpha4.Cluster{
+		Nodes: []v1alpha4.Node{
+			{Role: v1alpha4.ControlPlaneRole},
+			{Role: v1alpha4.ControlPlaneRole},
+			{Role: v1alpha4.WorkerRole, Image: "kindest/node:v1.20.7"},
+		},
+	}
+
+	if err := b.Create("test", config, CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := b.ListNodes("test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Node{
+		{Name: "test-control-plane", Role: "control-plane", KubernetesVersion: "v1.21.1",
+			ProviderID: "kind://fake/test/test-control-plane"},
+		{Name: "test-control-plane2", Role: "control-plane", KubernetesVersion: "v1.21.1",
+			ProviderID: "kind://fake/test/test-control-plane2"},
+		{Name: "test-worker", Role: "worker", KubernetesVersion: "v1.20.7",
+			ProviderID: "kind://fake/test/test-worker"},
+		{Name: "test-external-load-balancer", Role: "external-load-balancer"},
+	}
+
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("ListNodes() = %v, want %v", nodes, want)
+	}
+}
+
+func Test_FakeBackendLifecycle(t *testing.T) {
+	b := NewFakeBackend()
+
+	if err := b.Create("test", nil, CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Create("test", nil, CreateOptions{}); err == nil {
+		t.Errorf("Create() of an existing cluster error = nil, want error")
+	}
+
+	if _, err := b.KubeConfig("test", false); err != nil {
+		t.Errorf("KubeConfig() error = %v", err)
+	}
+
+	if err := b.Delete("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if clusters, _ := b.List(); len(clusters) != 0 {
+		t.Errorf("List() = %v, want no clusters", clusters)
+	}
+
+	b.CreateError = errors.New("failed")
+
+	if err := b.Create("test", nil, CreateOptions{}); err == nil {
+		t.Errorf("Create() error = nil, want %v", b.CreateError)
+	}
+}
+
+func Test_ImageVersion(t *testing.T) {
+	var testCases = []struct {
+		image   string
+		version string
+	}{
+		{"kindest/node:v1.21.1", "v1.21.1"},
+		{"kindest/node:v1.21.1@sha256:69860bda5563ac81e3c0057d654b5253219618a22ec3a346306239bba8cfa1a6", "v1.21.1"},
+		{"localhost:5000/kindest/node:v1.22.0", "v1.22.0"},
+		{"localhost:5000/kindest/node", ""},
+		{"kindest/node", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			if got := ImageVersion(tc.image); got != tc.version {
+				t.Errorf("ImageVersion() = %v, want %v", got, tc.version)
+			}
+		})
+	}
+}
+
+func Test_FakeBackendNodes(t *testing.T) {
+	b := NewFakeBackend()
+
+	if _, err := b.CreateNode("test", NodeOptions{Name: "test-extra"}); err == nil {
+		t.Errorf("CreateNode() in a missing cluster error = nil, want error")
+	}
+
+	if err := b.Create("test", nil, CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := b.CreateNode("test", NodeOptions{Name: "test-extra", Role: "worker", Machine: "extra"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if node.ProviderID != "kind://fake/test/test-extra" || node.KubernetesVersion != "v1.21.1" {
+		t.Errorf("CreateNode() = %+v, want a v1.21.1 node with a provider ID", node)
+	}
+
+	if nodes, _ := b.ListNodes("test"); len(nodes) != 2 || nodes[1].Machine != "extra" {
+		t.Errorf("ListNodes() = %v, want the control-plane and the extra node", nodes)
+	}
+
+	if err := b.DeleteNode("test", "test-extra"); err != nil {
+		t.Fatal(err)
+	}
+
+	if nodes, _ := b.ListNodes("test"); len(nodes) != 1 {
+		t.Errorf("ListNodes() = %v, want only the control-plane node", nodes)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// KindBackend is the ClusterBackend implementation that manages the clusters
+// by using the kind library
+type KindBackend struct {
+	provider *cluster.Provider
+}
+
+// NewKindBackend returns a ClusterBackend that uses the kind library
+func NewKindBackend() *KindBackend {
+	return &KindBackend{
+		provider: cluster.NewProvider(),
+	}
+}
+
+// List returns the names of the existing kind clusters
+func (b *KindBackend) List() ([]string, error) {
+	return b.provider.List()
+}
+
+// Create creates a kind cluster with the specified configuration
+func (b *KindBackend) Create(name string, config *v1alpha4.Cluster, options CreateOptions) error {
+	provider := b.provider
+
+	// The kind tool reports the steps of the creation as status logs, so a provider
+	// with a logger that captures them is used to report the progress
+	if options.OnStep != nil {
+		provider = cluster.NewProvider(cluster.ProviderWithLogger(&stepLogger{onStep: options.OnStep}))
+	}
+
+	// The kind tool always exports the kubeconfig of the created cluster to a file, it is
+	// exported to a private directory that is removed after the creation, because the
+	// kubeconfig is read with KubeConfig and the credentials should not be left on disk
+	dir, err := ioutil.TempDir("", "kind-kubeconfig-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	return provider.Create(name,
+		cluster.CreateWithKubeconfigPath(filepath.Join(dir, "config")),
+		cluster.CreateWithV1Alpha4Config(config))
+}
+
+// Delete deletes the kind cluster
+// The kind tool is idempotent and it does not return an error when it
+// cannot find the cluster
+func (b *KindBackend) Delete(name string) error {
+	return b.provider.Delete(name, "")
+}
+
+// KubeConfig returns the kubeconfig of the kind cluster
+func (b *KindBackend) KubeConfig(name string, internal bool) (string, error) {
+	return b.provider.KubeConfig(name, internal)
+}
+
+// ListNodes returns the node containers of the kind cluster with their roles, addresses,
+// machines and the Kubernetes versions installed on them
+func (b *KindBackend) ListNodes(name string) ([]Node, error) {
+	kindNodes, err := b.provider.ListNodes(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, 0, len(kindNodes))
+
+	for _, kindNode := range kindNodes {
+		role, err := kindNode.Role()
+
+		if err != nil {
+			return nil, err
+		}
+
+		node := Node{
+			Name: kindNode.String(),
+			Role: role,
+		}
+
+		// The load balancer node does not run Kubernetes
+		if role != externalLoadBalancerRole {
+			if node.KubernetesVersion, err = nodeutils.KubeVersion(kindNode); err != nil {
+				return nil, err
+			}
+
+			node.ProviderID = ProviderID(containerBinary(), name, node.Name)
+		}
+
+		if node.IPv4, node.IPv6, err = kindNode.IP(); err != nil {
+			return nil, err
+		}
+
+		if node.Machine, err = nodeMachine(node.Name); err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// stepLogger is a kind logger that passes the status messages of the kind tool,
+// which look like " • Preparing nodes 📦  ...", to a step callback
+// The other messages are discarded.
+type stepLogger struct {
+	onStep func(step string)
+}
+
+var _ log.Logger = &stepLogger{}
+
+func (l *stepLogger) Warn(message string) {}
+
+func (l *stepLogger) Warnf(format string, args ...interface{}) {}
+
+func (l *stepLogger) Error(message string) {}
+
+func (l *stepLogger) Errorf(format string, args ...interface{}) {}
+
+func (l *stepLogger) V(level log.Level) log.InfoLogger {
+	return l
+}
+
+func (l *stepLogger) Info(message string) {
+	if step := parseStep(message); step != "" {
+		l.onStep(step)
+	}
+}
+
+func (l *stepLogger) Infof(format string, args ...interface{}) {
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+func (l *stepLogger) Enabled() bool {
+	return true
+}
+
+// Get the step from a status message of the kind tool, it is empty for other messages
+func parseStep(message string) string {
+	message = strings.TrimSpace(message)
+
+	if !strings.HasPrefix(message, "•") || !strings.HasSuffix(message, "...") {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(message, "•"), "..."))
+}
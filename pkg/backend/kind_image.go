@@ -0,0 +1,77 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// LoadImage loads the image into the Kubernetes nodes of the kind cluster in the same way
+// as the load docker-image and the load image-archive commands of the kind tool
+// An image reference is saved to an archive with the container runtime first.
+func (b *KindBackend) LoadImage(clusterName, image string) error {
+	targets, err := b.kubernetesNodes(clusterName)
+
+	if err != nil {
+		return err
+	}
+
+	archive := image
+
+	if !IsImageArchive(image) {
+		dir, err := ioutil.TempDir("", "kind-image-")
+
+		if err != nil {
+			return err
+		}
+
+		defer os.RemoveAll(dir)
+
+		archive = filepath.Join(dir, "image.tar")
+
+		if err := exec.Command(containerBinary(), "save", "-o", archive, image).Run(); err != nil {
+			return fmt.Errorf("failed to save image %s: %w", image, err)
+		}
+	}
+
+	f, err := os.Open(archive)
+
+	if err != nil {
+		return fmt.Errorf("failed to open image archive %s: %w", archive, err)
+	}
+
+	defer f.Close()
+
+	for _, target := range targets {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		if err := nodeutils.LoadImageArchive(target, f); err != nil {
+			return fmt.Errorf("failed to load image %s into node %s: %w", image, target.String(), err)
+		}
+	}
+
+	return nil
+}
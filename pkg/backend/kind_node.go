@@ -0,0 +1,502 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// The kind library does not add nodes to an existing cluster, so the node containers
+// are created with the same options as the kind tool uses and joined with kubeadm
+const (
+	// Labels that the kind tool uses to find the node containers of a cluster
+	clusterLabelKey = "io.x-k8s.kind.cluster"
+	roleLabelKey    = "io.x-k8s.kind.role"
+
+	// Label of the node containers that belong to a machine
+	machineLabelKey = "infrastructure.cluster-k8s.io/kindmachine"
+
+	// The network that the kind tool creates for the node containers
+	defaultNetwork = "kind"
+
+	// The file that the join configuration is written to in the node container
+	joinConfigPath = "/kind/kubeadm-join.conf"
+
+	// The configuration of the container runtime in the node containers, it contains the
+	// containerd configuration patches of the cluster
+	containerdConfigPath = "/etc/containerd/config.toml"
+
+	// The kubelet reads the credentials of the private registries from this file, see
+	// https://kind.sigs.k8s.io/docs/user/private-registries/
+	registryAuthPath = "/var/lib/kubelet/config.json"
+
+	// The time to wait for the container runtime in a new node container
+	containerdTimeout = 30 * time.Second
+
+	// The port of the API server in the node containers
+	apiServerPort = 6443
+)
+
+// joinConfiguration is the subset of the kubeadm JoinConfiguration that joins a node
+// container, it is marshalled instead of formatted so that the values are escaped
+type joinConfiguration struct {
+	APIVersion       string               `yaml:"apiVersion"`
+	Kind             string               `yaml:"kind"`
+	ControlPlane     *joinControlPlane    `yaml:"controlPlane,omitempty"`
+	NodeRegistration joinNodeRegistration `yaml:"nodeRegistration"`
+	Discovery        joinDiscovery        `yaml:"discovery"`
+}
+
+type joinControlPlane struct {
+	LocalAPIEndpoint joinAPIEndpoint `yaml:"localAPIEndpoint"`
+	CertificateKey   string          `yaml:"certificateKey"`
+}
+
+type joinAPIEndpoint struct {
+	AdvertiseAddress string `yaml:"advertiseAddress"`
+	BindPort         int32  `yaml:"bindPort"`
+}
+
+type joinNodeRegistration struct {
+	CRISocket        string            `yaml:"criSocket"`
+	KubeletExtraArgs map[string]string `yaml:"kubeletExtraArgs"`
+}
+
+type joinDiscovery struct {
+	BootstrapToken joinBootstrapToken `yaml:"bootstrapToken"`
+}
+
+type joinBootstrapToken struct {
+	APIServerEndpoint        string `yaml:"apiServerEndpoint"`
+	Token                    string `yaml:"token"`
+	UnsafeSkipCAVerification bool   `yaml:"unsafeSkipCAVerification"`
+}
+
+// CreateNode creates a node container with the options of the kind tool and joins it
+// to the cluster with kubeadm
+// A control-plane node can only be added to a cluster with a load balancer in front
+// of its control-plane nodes, that is a cluster with multiple control-plane nodes.
+func (b *KindBackend) CreateNode(clusterName string, options NodeOptions) (Node, error) {
+	if err := ValidateNodeLabels(options.Labels); err != nil {
+		return Node{}, err
+	}
+
+	if err := ValidateNodeMounts(options.ExtraMounts); err != nil {
+		return Node{}, err
+	}
+
+	existing, err := b.provider.ListNodes(clusterName)
+
+	if err != nil {
+		return Node{}, err
+	}
+
+	if len(existing) == 0 {
+		return Node{}, fmt.Errorf("cluster %q does not exist", clusterName)
+	}
+
+	controlPlanes, err := nodeutils.ControlPlaneNodes(existing)
+
+	if err != nil {
+		return Node{}, err
+	}
+
+	if len(controlPlanes) == 0 {
+		return Node{}, fmt.Errorf("cluster %q does not have a control-plane node", clusterName)
+	}
+
+	endpointNode, err := nodeutils.APIServerEndpointNode(existing)
+
+	if err != nil {
+		return Node{}, err
+	}
+
+	if options.Role == string(v1alpha4.ControlPlaneRole) && endpointNode.String() == controlPlanes[0].String() {
+		return Node{}, fmt.Errorf("cluster %q does not have a load balancer, a control-plane node cannot be added", clusterName)
+	}
+
+	bootstrapNode := controlPlanes[0]
+
+	runArgs := nodeRunArgs(clusterName, options)
+
+	if err := exec.Command(containerBinary(), runArgs...).Run(); err != nil {
+		return Node{}, fmt.Errorf("failed to create node container %s: %w", options.Name, err)
+	}
+
+	node, err := b.joinNode(clusterName, options, bootstrapNode, endpointNode.String())
+
+	if err != nil {
+		// Do not leave a node container behind that is not part of the cluster
+		_ = exec.Command(containerBinary(), "rm", "-f", "-v", options.Name).Run()
+
+		return Node{}, err
+	}
+
+	return node, nil
+}
+
+// Join the new node container to the cluster and return the node
+func (b *KindBackend) joinNode(clusterName string, options NodeOptions, bootstrapNode nodes.Node, endpoint string) (Node, error) {
+	kindNode, err := b.findNode(clusterName, options.Name)
+
+	if err != nil {
+		return Node{}, err
+	}
+
+	if err := waitForContainerd(kindNode); err != nil {
+		return Node{}, err
+	}
+
+	if err := inheritNodeConfig(bootstrapNode, kindNode); err != nil {
+		return Node{}, err
+	}
+
+	ipv4, ipv6, err := kindNode.IP()
+
+	if err != nil {
+		return Node{}, fmt.Errorf("failed to get the address of node %s: %w", options.Name, err)
+	}
+
+	nodeIP := ipv4
+
+	if nodeIP == "" {
+		nodeIP = ipv6
+	}
+
+	token, err := lastLine(bootstrapNode.Command("kubeadm", "token", "create", "--ttl", "15m"))
+
+	if err != nil {
+		return Node{}, fmt.Errorf("failed to create a bootstrap token: %w", err)
+	}
+
+	var controlPlane *joinControlPlane
+
+	if options.Role == string(v1alpha4.ControlPlaneRole) {
+		// The certificates of the control plane are shared with the new node
+		// through an encrypted secret in the cluster
+		key, err := lastLine(bootstrapNode.Command("kubeadm", "init", "phase", "upload-certs", "--upload-certs"))
+
+		if err != nil {
+			return Node{}, fmt.Errorf("failed to upload the control plane certificates: %w", err)
+		}
+
+		controlPlane = &joinControlPlane{
+			LocalAPIEndpoint: joinAPIEndpoint{AdvertiseAddress: nodeIP, BindPort: apiServerPort},
+			CertificateKey:   key,
+		}
+	}
+
+	providerID := ProviderID(containerBinary(), clusterName, options.Name)
+
+	joinConfig, err := marshalJoinConfig(controlPlane, nodeIP, providerID, options.Labels,
+		net.JoinHostPort(endpoint, fmt.Sprint(apiServerPort)), token)
+
+	if err != nil {
+		return Node{}, err
+	}
+
+	if err := nodeutils.WriteFile(kindNode, joinConfigPath, joinConfig); err != nil {
+		return Node{}, err
+	}
+
+	lines, err := exec.CombinedOutputLines(kindNode.Command("kubeadm", "join",
+		"--config", joinConfigPath,
+		// The preflight checks fail in the node containers, the kind tool also skips them
+		"--skip-phases=preflight",
+		"--v=6"))
+
+	if err != nil {
+		return Node{}, fmt.Errorf("failed to join node %s with kubeadm: %w: %s", options.Name, err, strings.Join(lines, "\n"))
+	}
+
+	version, err := nodeutils.KubeVersion(kindNode)
+
+	if err != nil {
+		return Node{}, err
+	}
+
+	return Node{
+		Name:              options.Name,
+		Role:              options.Role,
+		KubernetesVersion: version,
+		IPv4:              ipv4,
+		IPv6:              ipv6,
+		Machine:           options.Machine,
+		ProviderID:        providerID,
+	}, nil
+}
+
+// DeleteNode removes the node from the cluster and deletes its container
+func (b *KindBackend) DeleteNode(clusterName, nodeName string) error {
+	existing, err := b.provider.ListNodes(clusterName)
+
+	if err != nil {
+		return err
+	}
+
+	var node nodes.Node
+	var others []nodes.Node
+
+	for _, n := range existing {
+		if n.String() == nodeName {
+			node = n
+		} else {
+			others = append(others, n)
+		}
+	}
+
+	if node == nil {
+		return nil
+	}
+
+	// The node is removed from the API server of the cluster through another
+	// control-plane node, it is not an error if the cluster is not reachable
+	if controlPlanes, err := nodeutils.ControlPlaneNodes(others); err == nil && len(controlPlanes) > 0 {
+		_ = controlPlanes[0].Command("kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+			"delete", "node", nodeName, "--ignore-not-found").Run()
+	}
+
+	return exec.Command(containerBinary(), "rm", "-f", "-v", nodeName).Run()
+}
+
+// Copy the containerd configuration and the registry credentials of the existing node to the
+// new node, so the new node pulls the images through the same mirrors and registries
+// The containerd configuration patches of the cluster are only applied by the kind tool to
+// the nodes that it creates.
+func inheritNodeConfig(existing, node nodes.Node) error {
+	if err := nodeutils.CopyNodeToNode(existing, node, containerdConfigPath); err != nil {
+		return err
+	}
+
+	if err := node.Command("systemctl", "restart", "containerd").Run(); err != nil {
+		return fmt.Errorf("failed to restart container runtime of node %s: %w", node.String(), err)
+	}
+
+	if err := waitForContainerd(node); err != nil {
+		return err
+	}
+
+	// The kubelet is started by kubeadm join, so it reads the credentials without a restart
+	if existing.Command("test", "-f", registryAuthPath).Run() == nil {
+		if err := nodeutils.CopyNodeToNode(existing, node, registryAuthPath); err != nil {
+			return fmt.Errorf("failed to copy registry credentials to node %s", node.String())
+		}
+	}
+
+	return nil
+}
+
+// Get the node containers of the cluster that run Kubernetes, the load balancer node is skipped
+func (b *KindBackend) kubernetesNodes(clusterName string) ([]nodes.Node, error) {
+	kindNodes, err := b.provider.ListNodes(clusterName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var kubernetesNodes []nodes.Node
+
+	for _, kindNode := range kindNodes {
+		role, err := kindNode.Role()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if role != externalLoadBalancerRole {
+			kubernetesNodes = append(kubernetesNodes, kindNode)
+		}
+	}
+
+	if len(kubernetesNodes) == 0 {
+		return nil, fmt.Errorf("cluster %q does not have any nodes", clusterName)
+	}
+
+	return kubernetesNodes, nil
+}
+
+// Find the node container of the cluster with the specified name
+func (b *KindBackend) findNode(clusterName, nodeName string) (nodes.Node, error) {
+	kindNodes, err := b.provider.ListNodes(clusterName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kindNode := range kindNodes {
+		if kindNode.String() == nodeName {
+			return kindNode, nil
+		}
+	}
+
+	return nil, fmt.Errorf("node %s of cluster %q cannot be found", nodeName, clusterName)
+}
+
+// Get the machine that the node container belongs to from its labels
+func nodeMachine(nodeName string) (string, error) {
+	return lastLine(exec.Command(containerBinary(), "inspect",
+		"--format", fmt.Sprintf(`{{ index .Config.Labels %q }}`, machineLabelKey), nodeName))
+}
+
+// Get the arguments of the container run command of a node, they are the same
+// as the ones that the kind tool uses for its node containers
+func nodeRunArgs(clusterName string, options NodeOptions) []string {
+	network := nodeNetwork()
+
+	args := []string{
+		"run",
+		"--hostname", options.Name,
+		"--name", options.Name,
+		"--label", fmt.Sprintf("%s=%s", clusterLabelKey, clusterName),
+		"--label", fmt.Sprintf("%s=%s", roleLabelKey, options.Role),
+		"--privileged",
+		"--security-opt", "seccomp=unconfined",
+		"--security-opt", "apparmor=unconfined",
+		"--tmpfs", "/tmp",
+		"--tmpfs", "/run",
+		"--volume", "/var",
+		"--volume", "/lib/modules:/lib/modules:ro",
+		"--detach",
+		"--tty",
+		"--net", network,
+		"--restart=on-failure:1",
+		"--init=false",
+	}
+
+	if options.Machine != "" {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", machineLabelKey, options.Machine))
+	}
+
+	for _, mount := range options.ExtraMounts {
+		volume := fmt.Sprintf("%s:%s", mount.HostPath, mount.ContainerPath)
+
+		if mount.Readonly {
+			volume += ":ro"
+		}
+
+		args = append(args, "--volume", volume)
+	}
+
+	if options.Role == string(v1alpha4.ControlPlaneRole) {
+		args = append(args, "-e", "KUBECONFIG=/etc/kubernetes/admin.conf")
+	}
+
+	return append(args, options.Image)
+}
+
+// Marshal the kubeadm join configuration of a node, the control plane is only set for
+// the control-plane nodes
+func marshalJoinConfig(controlPlane *joinControlPlane, nodeIP, providerID string, labels map[string]string,
+	apiServerEndpoint, token string) (string, error) {
+	kubeletArgs := map[string]string{
+		"fail-swap-on": "false",
+		"node-ip":      nodeIP,
+		"provider-id":  providerID,
+	}
+
+	if len(labels) > 0 {
+		kubeletArgs["node-labels"] = nodeLabels(labels)
+	}
+
+	data, err := yaml.Marshal(joinConfiguration{
+		APIVersion:   "kubeadm.k8s.io/v1beta2",
+		Kind:         "JoinConfiguration",
+		ControlPlane: controlPlane,
+		NodeRegistration: joinNodeRegistration{
+			CRISocket:        "unix:///run/containerd/containerd.sock",
+			KubeletExtraArgs: kubeletArgs,
+		},
+		Discovery: joinDiscovery{BootstrapToken: joinBootstrapToken{
+			APIServerEndpoint:        apiServerEndpoint,
+			Token:                    token,
+			UnsafeSkipCAVerification: true,
+		}},
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal join configuration: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Get the node labels in the format of the kubelet flag: key1=value1,key2=value2
+func nodeLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Get the container runtime binary that the kind tool is configured to use
+func containerBinary() string {
+	if os.Getenv("KIND_EXPERIMENTAL_PROVIDER") == "podman" {
+		return "podman"
+	}
+
+	return "docker"
+}
+
+// Wait until the container runtime in the node container is running
+func waitForContainerd(node nodes.Node) error {
+	deadline := time.Now().Add(containerdTimeout)
+
+	for {
+		err := node.Command("systemctl", "is-active", "--quiet", "containerd").Run()
+
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container runtime of node %s is not running: %w", node.String(), err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// Run the command and return the last line of its output
+func lastLine(cmd exec.Cmd) (string, error) {
+	lines, err := exec.OutputLines(cmd)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// EnsureRegistry creates the registry container in the same way as the local registry
+// guide of kind does, and connects it to the network of the nodes
+// The network is created by kind with the first cluster, so the registry is connected
+// after a cluster was created.
+func (b *KindBackend) EnsureRegistry(options RegistryOptions) error {
+	running, err := lastLine(exec.Command(containerBinary(), "inspect", "--format", "{{ .State.Running }}", options.Name))
+
+	switch {
+	case err != nil:
+		// The registry container does not exist
+		if err := exec.Command(containerBinary(), registryRunArgs(options)...).Run(); err != nil {
+			return fmt.Errorf("failed to create registry container %s: %w", options.Name, err)
+		}
+	case running != "true":
+		if err := exec.Command(containerBinary(), "start", options.Name).Run(); err != nil {
+			return fmt.Errorf("failed to start registry container %s: %w", options.Name, err)
+		}
+	}
+
+	// The registry is shared by name, a registry of another cluster may be published on
+	// another port, then the mirror of the nodes would not point to it
+	hostPort, err := lastLine(exec.Command(containerBinary(), "inspect", "--format",
+		fmt.Sprintf(`{{ range (index .HostConfig.PortBindings "%d/tcp") }}{{ .HostPort }}{{ end }}`, RegistryContainerPort),
+		options.Name))
+
+	if err != nil {
+		return fmt.Errorf("failed to get host port of registry container %s: %w", options.Name, err)
+	}
+
+	if hostPort != fmt.Sprint(options.HostPort) {
+		return fmt.Errorf("registry container %s is published on host port %s, not %d", options.Name, hostPort, options.HostPort)
+	}
+
+	network := nodeNetwork()
+
+	networks, err := lastLine(exec.Command(containerBinary(), "inspect", "--format",
+		"{{ range $name, $_ := .NetworkSettings.Networks }}{{ $name }} {{ end }}", options.Name))
+
+	if err != nil {
+		return fmt.Errorf("failed to get networks of registry container %s: %w", options.Name, err)
+	}
+
+	for _, connected := range strings.Fields(networks) {
+		if connected == network {
+			return nil
+		}
+	}
+
+	if err := exec.Command(containerBinary(), "network", "connect", network, options.Name).Run(); err != nil {
+		return fmt.Errorf("failed to connect registry container %s to network %s: %w", options.Name, network, err)
+	}
+
+	return nil
+}
+
+// ApplyManifest applies the manifest with kubectl on the first control-plane node, with the
+// admin kubeconfig of the node
+func (b *KindBackend) ApplyManifest(clusterName, manifest string) error {
+	kindNodes, err := b.provider.ListNodes(clusterName)
+
+	if err != nil {
+		return err
+	}
+
+	controlPlanes, err := nodeutils.ControlPlaneNodes(kindNodes)
+
+	if err != nil {
+		return err
+	}
+
+	if len(controlPlanes) == 0 {
+		return fmt.Errorf("cluster %q does not have a control-plane node", clusterName)
+	}
+
+	cmd := controlPlanes[0].Command("kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "apply", "-f", "-")
+	cmd.SetStdin(strings.NewReader(manifest))
+
+	lines, err := exec.CombinedOutputLines(cmd)
+
+	if err != nil {
+		return fmt.Errorf("failed to apply manifest to cluster %q: %w: %s", clusterName, err, strings.Join(lines, "\n"))
+	}
+
+	return nil
+}
+
+// WriteRegistryAuth writes the registry credentials into all Kubernetes nodes of the kind
+// cluster and restarts their kubelets, as the private registries guide of kind does
+// The credentials are passed to the nodes through the standard input, so they are not
+// part of the commands or their errors.
+func (b *KindBackend) WriteRegistryAuth(clusterName string, dockerConfig []byte) error {
+	kubernetesNodes, err := b.kubernetesNodes(clusterName)
+
+	if err != nil {
+		return err
+	}
+
+	for _, node := range kubernetesNodes {
+		if err := nodeutils.WriteFile(node, registryAuthPath, string(dockerConfig)); err != nil {
+			return fmt.Errorf("failed to write registry credentials to node %s", node.String())
+		}
+
+		if err := node.Command("systemctl", "restart", "kubelet").Run(); err != nil {
+			return fmt.Errorf("failed to restart kubelet of node %s: %w", node.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// Get the arguments of the container run command of the registry
+func registryRunArgs(options RegistryOptions) []string {
+	return []string{
+		"run",
+		"--detach",
+		"--restart=always",
+		"--name", options.Name,
+		"--publish", fmt.Sprintf("127.0.0.1:%d:%d", options.HostPort, RegistryContainerPort),
+		options.Image,
+	}
+}
+
+// Get the network of the node containers
+func nodeNetwork() string {
+	if network := os.Getenv("KIND_EXPERIMENTAL_DOCKER_NETWORK"); network != "" {
+		return network
+	}
+
+	return defaultNetwork
+}
@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+func Test_ParseStep(t *testing.T) {
+	var testCases = []struct {
+		message string
+		step    string
+	}{
+		{" • Preparing nodes 📦  ...\n", "Preparing nodes 📦"},
+		{" ✓ Preparing nodes 📦\n", ""},
+		{"Creating cluster \"test\" ...\n", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.message, func(t *testing.T) {
+			if got := parseStep(tc.message); got != tc.step {
+				t.Errorf("parseStep() = %q, want %q", got, tc.step)
+			}
+		})
+	}
+}
+
+func Test_StepLogger(t *testing.T) {
+	var steps []string
+
+	logger := &stepLogger{onStep: func(step string) {
+		steps = append(steps, step)
+	}}
+
+	logger.V(0).Infof(" • %s  ...\n", "Starting control-plane 🕹️")
+	logger.V(0).Info("Set kubectl context to \"kind-test\"")
+
+	if len(steps) != 1 || steps[0] != "Starting control-plane 🕹️" {
+		t.Errorf("stepLogger steps = %v, want [Starting control-plane 🕹️]", steps)
+	}
+}
+
+func Test_NodeLabels(t *testing.T) {
+	got := nodeLabels(map[string]string{"tier": "workload", "app": "test"})
+
+	if want := "app=test,tier=workload"; got != want {
+		t.Errorf("nodeLabels() = %v, want %v", got, want)
+	}
+}
+
+func Test_ValidateNodeLabels(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		labels  map[string]string
+		wantErr bool
+	}{
+		{"valid labels", map[string]string{"tier": "workload", "example.com/app": "test"}, false},
+		{"injected label", map[string]string{"tier": "workload,node-role.kubernetes.io/master="}, true},
+		{"invalid key", map[string]string{"tier\"": "workload"}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateNodeLabels(tc.labels); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateNodeLabels() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ValidateNodeMounts(t *testing.T) {
+	var testCases = []struct {
+		name    string
+		mounts  []v1alpha4.Mount
+		wantErr bool
+	}{
+		{"absolute paths", []v1alpha4.Mount{{HostPath: "/data", ContainerPath: "/data"}}, false},
+		{"relative host path", []v1alpha4.Mount{{HostPath: "data", ContainerPath: "/data"}}, true},
+		{"relative container path", []v1alpha4.Mount{{HostPath: "/data", ContainerPath: "data"}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateNodeMounts(tc.mounts); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateNodeMounts() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func Test_MarshalJoinConfig(t *testing.T) {
+	controlPlane := &joinControlPlane{
+		LocalAPIEndpoint: joinAPIEndpoint{AdvertiseAddress: "172.18.0.5", BindPort: apiServerPort},
+		CertificateKey:   "key",
+	}
+
+	got, err := marshalJoinConfig(controlPlane, "172.18.0.5", "kind://docker/test/test-extra",
+		map[string]string{"tier": "workload"}, "test-external-load-balancer:6443", "token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config joinConfiguration
+
+	if err := yaml.Unmarshal([]byte(got), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Kind != "JoinConfiguration" || config.ControlPlane == nil ||
+		config.ControlPlane.LocalAPIEndpoint.AdvertiseAddress != "172.18.0.5" ||
+		config.NodeRegistration.KubeletExtraArgs["node-labels"] != "tier=workload" ||
+		config.Discovery.BootstrapToken.APIServerEndpoint != "test-external-load-balancer:6443" {
+		t.Errorf("marshalJoinConfig() = %v, want the join configuration of the control-plane node", got)
+	}
+}
+
+func Test_NodeRunArgs(t *testing.T) {
+	args := nodeRunArgs("test", NodeOptions{
+		Name:        "test-extra",
+		Role:        "worker",
+		Image:       "kindest/node:v1.21.1",
+		Machine:     "extra",
+		ExtraMounts: []v1alpha4.Mount{{HostPath: "/data", ContainerPath: "/data", Readonly: true}},
+	})
+
+	joined := strings.Join(args, " ")
+
+	for _, want := range []string{
+		"--name test-extra",
+		"--label io.x-k8s.kind.cluster=test",
+		"--label io.x-k8s.kind.role=worker",
+		"--label infrastructure.cluster-k8s.io/kindmachine=extra",
+		"--volume /data:/data:ro",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("nodeRunArgs() = %v, want %q", joined, want)
+		}
+	}
+
+	if args[len(args)-1] != "kindest/node:v1.21.1" {
+		t.Errorf("nodeRunArgs() last argument = %v, want the image", args[len(args)-1])
+	}
+}